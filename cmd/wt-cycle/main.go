@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/sestinj/wt-cycle/internal/cmd"
@@ -11,6 +12,10 @@ var version = "dev" // overridden by ldflags at build time
 func main() {
 	cmd.SetVersion(version)
 	if err := cmd.Execute(); err != nil {
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,173 @@
+package archive
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/git"
+)
+
+// Prefix is the ref namespace holding archived branch tips. Refs outside
+// refs/heads and refs/tags are not fetched, pushed or listed by default,
+// but keep their commits reachable so gc never drops them.
+const Prefix = "refs/wt-cycle/archive/"
+
+// PinnedPrefix holds archives that Prune never removes, such as branches
+// whose PR was closed without merging.
+const PinnedPrefix = "refs/wt-cycle/pinned/"
+
+// DefaultRetention is how long Prune keeps unpinned archives.
+const DefaultRetention = 30 * 24 * time.Hour
+
+// Save records branch's current tip as Prefix<branch>/<unix time> so its
+// commits survive `git branch -D`. It returns the archive ref name.
+func Save(g git.Client, branch string, now time.Time) (string, error) {
+	return save(g, Prefix, branch, now)
+}
+
+// Pin is like Save but writes under PinnedPrefix.
+func Pin(g git.Client, branch string, now time.Time) (string, error) {
+	return save(g, PinnedPrefix, branch, now)
+}
+
+func save(g git.Client, prefix, branch string, now time.Time) (string, error) {
+	sha, err := g.ResolveRef("refs/heads/" + branch)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", branch, err)
+	}
+	ref := fmt.Sprintf("%s%s/%d", prefix, branch, now.Unix())
+	if err := g.UpdateRef(ref, sha); err != nil {
+		return "", fmt.Errorf("archiving %s: %w", branch, err)
+	}
+	return ref, nil
+}
+
+// Entry is an archived branch tip.
+type Entry struct {
+	Ref    string
+	ID     string // "<branch>/<unix time>", the ref without its namespace
+	Branch string
+	Time   time.Time
+	SHA    string
+	Pinned bool
+}
+
+// List returns all archived branch tips, oldest first.
+func List(g git.Client) ([]Entry, error) {
+	refs, err := g.ListRefs(Prefix, PinnedPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, r := range refs {
+		e, ok := parseEntry(r)
+		if ok {
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
+
+// parseEntry splits an archive ref into its branch and timestamp.
+func parseEntry(r git.Ref) (Entry, bool) {
+	e := Entry{Ref: r.Name, SHA: r.SHA}
+	var ok bool
+	if e.ID, ok = strings.CutPrefix(r.Name, Prefix); !ok {
+		if e.ID, ok = strings.CutPrefix(r.Name, PinnedPrefix); !ok {
+			return Entry{}, false
+		}
+		e.Pinned = true
+	}
+	i := strings.LastIndex(e.ID, "/")
+	if i <= 0 {
+		return Entry{}, false
+	}
+	unix, err := strconv.ParseInt(e.ID[i+1:], 10, 64)
+	if err != nil {
+		return Entry{}, false
+	}
+	e.Branch = e.ID[:i]
+	e.Time = time.Unix(unix, 0)
+	return e, true
+}
+
+// Find resolves name to an archive entry. name may be an archive ID, a full
+// archive ref, or a branch name, which selects that branch's newest archive.
+func Find(g git.Client, name string) (Entry, error) {
+	entries, err := List(g)
+	if err != nil {
+		return Entry{}, fmt.Errorf("listing archives: %w", err)
+	}
+	var newest *Entry
+	for i, e := range entries {
+		if e.ID == name || e.Ref == name {
+			return e, nil
+		}
+		if e.Branch == name {
+			newest = &entries[i]
+		}
+	}
+	if newest == nil {
+		return Entry{}, fmt.Errorf("no archive found for %q", name)
+	}
+	return *newest, nil
+}
+
+// Prune deletes unpinned archives older than retention and returns them.
+func Prune(g git.Client, now time.Time, retention time.Duration) ([]Entry, error) {
+	entries, err := List(g)
+	if err != nil {
+		return nil, fmt.Errorf("listing archives: %w", err)
+	}
+	cutoff := now.Add(-retention)
+	var pruned []Entry
+	for _, e := range entries {
+		if e.Pinned || !e.Time.Before(cutoff) {
+			continue
+		}
+		if err := g.DeleteRef(e.Ref); err != nil {
+			return pruned, fmt.Errorf("deleting %s: %w", e.Ref, err)
+		}
+		pruned = append(pruned, e)
+	}
+	return pruned, nil
+}
+
+// StashPrefix is the ref namespace holding stashes converted by SaveStashes.
+const StashPrefix = "refs/wt-cycle/stash/"
+
+// SaveStashes records each stash commit as StashPrefix<branch>/<unix time>-<n>
+// and then drops it from the stash list. It returns the refs written.
+func SaveStashes(g git.Client, stashes []git.Stash, now time.Time) ([]string, error) {
+	var refs []string
+	for i, s := range stashes {
+		ref := fmt.Sprintf("%s%s/%d-%d", StashPrefix, s.Branch, now.Unix(), i)
+		if err := g.UpdateRef(ref, s.SHA); err != nil {
+			return refs, fmt.Errorf("archiving %s: %w", s.Ref, err)
+		}
+		refs = append(refs, ref)
+	}
+
+	// Dropping renumbers later entries, so go from the highest index down.
+	sorted := append([]git.Stash(nil), stashes...)
+	sort.Slice(sorted, func(i, j int) bool { return stashIndex(sorted[i].Ref) > stashIndex(sorted[j].Ref) })
+	for _, s := range sorted {
+		if _, err := g.Run("stash", "drop", "-q", s.Ref); err != nil {
+			return refs, fmt.Errorf("dropping %s: %w", s.Ref, err)
+		}
+	}
+	return refs, nil
+}
+
+// stashIndex returns N from "stash@{N}", or -1.
+func stashIndex(ref string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(ref, "stash@{"), "}"))
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,162 @@
+package archive
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/git"
+)
+
+// fakeGit implements the parts of git.Client used by this package.
+type fakeGit struct {
+	git.Client
+	shas    map[string]string
+	updated map[string]string
+	runs    [][]string
+	refs    []git.Ref
+	deleted []string
+}
+
+func (f *fakeGit) ListRefs(_ ...string) ([]git.Ref, error) {
+	return f.refs, nil
+}
+
+func (f *fakeGit) DeleteRef(ref string) error {
+	f.deleted = append(f.deleted, ref)
+	return nil
+}
+
+func (f *fakeGit) Run(args ...string) (string, error) {
+	f.runs = append(f.runs, args)
+	return "", nil
+}
+
+func (f *fakeGit) ResolveRef(ref string) (string, error) {
+	if sha, ok := f.shas[ref]; ok {
+		return sha, nil
+	}
+	return "", fmt.Errorf("unknown ref %s", ref)
+}
+
+func (f *fakeGit) UpdateRef(ref, sha string) error {
+	f.updated[ref] = sha
+	return nil
+}
+
+func TestSave(t *testing.T) {
+	g := &fakeGit{shas: map[string]string{"refs/heads/wt-3": "abc123"}, updated: map[string]string{}}
+
+	ref, err := Save(g, "wt-3", time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != "refs/wt-cycle/archive/wt-3/1700000000" {
+		t.Errorf("ref = %q", ref)
+	}
+	if g.updated[ref] != "abc123" {
+		t.Errorf("archived sha = %q, want abc123", g.updated[ref])
+	}
+}
+
+func TestPin(t *testing.T) {
+	g := &fakeGit{shas: map[string]string{"refs/heads/wt-3": "abc123"}, updated: map[string]string{}}
+
+	ref, err := Pin(g, "wt-3", time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != "refs/wt-cycle/pinned/wt-3/1700000000" {
+		t.Errorf("ref = %q", ref)
+	}
+}
+
+func TestFind(t *testing.T) {
+	g := &fakeGit{refs: []git.Ref{
+		{Name: "refs/wt-cycle/archive/wt-3/1700000200", SHA: "newer"},
+		{Name: "refs/wt-cycle/archive/wt-3/1700000100", SHA: "older"},
+		{Name: "refs/wt-cycle/pinned/agent/bob/2/1700000000", SHA: "pinned"},
+		{Name: "refs/wt-cycle/archive/not-an-archive", SHA: "junk"},
+	}}
+
+	tests := []struct {
+		name, wantSHA, wantBranch string
+	}{
+		{"wt-3", "newer", "wt-3"},
+		{"wt-3/1700000100", "older", "wt-3"},
+		{"refs/wt-cycle/archive/wt-3/1700000100", "older", "wt-3"},
+		{"agent/bob/2", "pinned", "agent/bob/2"},
+	}
+	for _, tt := range tests {
+		e, err := Find(g, tt.name)
+		if err != nil {
+			t.Errorf("Find(%q): %v", tt.name, err)
+			continue
+		}
+		if e.SHA != tt.wantSHA || e.Branch != tt.wantBranch {
+			t.Errorf("Find(%q) = %+v, want sha %s on %s", tt.name, e, tt.wantSHA, tt.wantBranch)
+		}
+	}
+
+	if _, err := Find(g, "wt-9"); err == nil {
+		t.Error("expected error for a branch with no archive")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	old := now.Add(-31 * 24 * time.Hour).Unix()
+	recent := now.Add(-time.Hour).Unix()
+	g := &fakeGit{refs: []git.Ref{
+		{Name: fmt.Sprintf("refs/wt-cycle/archive/wt-1/%d", old)},
+		{Name: fmt.Sprintf("refs/wt-cycle/archive/wt-2/%d", recent)},
+		{Name: fmt.Sprintf("refs/wt-cycle/pinned/wt-3/%d", old)},
+	}}
+
+	pruned, err := Prune(g, now, DefaultRetention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 1 || pruned[0].Branch != "wt-1" {
+		t.Fatalf("pruned = %+v, want only wt-1", pruned)
+	}
+	if len(g.deleted) != 1 || g.deleted[0] != pruned[0].Ref {
+		t.Errorf("deleted = %v", g.deleted)
+	}
+}
+
+func TestSave_MissingBranch(t *testing.T) {
+	g := &fakeGit{updated: map[string]string{}}
+
+	if _, err := Save(g, "wt-9", time.Now()); err == nil {
+		t.Fatal("expected error for a missing branch")
+	}
+	if len(g.updated) != 0 {
+		t.Errorf("no ref should be written, got %v", g.updated)
+	}
+}
+
+func TestSaveStashes(t *testing.T) {
+	g := &fakeGit{updated: map[string]string{}}
+	stashes := []git.Stash{
+		{Ref: "stash@{1}", SHA: "aaa", Branch: "wt-3"},
+		{Ref: "stash@{4}", SHA: "bbb", Branch: "wt-3"},
+		{Ref: "stash@{2}", SHA: "ccc", Branch: "wt-5"},
+	}
+
+	refs, err := SaveStashes(g, stashes, time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 3 || refs[1] != "refs/wt-cycle/stash/wt-3/1700000000-1" || g.updated[refs[1]] != "bbb" {
+		t.Errorf("refs = %v, updated = %v", refs, g.updated)
+	}
+
+	var dropped []string
+	for _, r := range g.runs {
+		dropped = append(dropped, r[len(r)-1])
+	}
+	if fmt.Sprint(dropped) != "[stash@{4} stash@{2} stash@{1}]" {
+		t.Errorf("dropped %v, want highest index first", dropped)
+	}
+}
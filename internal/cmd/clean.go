@@ -1,21 +1,39 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"sort"
 
+	"github.com/sestinj/wt-cycle/internal/config"
 	"github.com/sestinj/wt-cycle/internal/cycle"
 	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/hooks"
 	"github.com/spf13/cobra"
 )
 
 var cleanCmd = &cobra.Command{
 	Use:   "clean",
 	Short: "Remove all recyclable worktrees",
-	Long:  "Finds all recyclable worktrees (merged/closed PR, clean working tree) and removes them.",
-	RunE:  runClean,
+	Long: "Finds all recyclable worktrees (merged/closed PR, clean working tree) and removes them. " +
+		"With --interactive, lists them with their reason, last commit and disk usage to pick which to remove; " +
+		"when stdin is not a terminal it asks y/N for each instead.",
+	RunE: runClean,
 }
 
+// archiveStashes and dryRun are shared by next and clean.
+var (
+	archiveStashes bool
+	dryRun         bool
+)
+
+var cleanInteractive bool
+
 func init() {
+	cleanCmd.Flags().BoolVarP(&cleanInteractive, "interactive", "i", false, "choose which recyclable worktrees to remove")
+	cleanCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "print the git and wt commands clean would run, without running them")
+	cleanCmd.Flags().BoolVar(&archiveStashes, "archive-stashes", false, "archive stashes on recyclable branches under refs/wt-cycle/stash/ instead of skipping them")
 	rootCmd.AddCommand(cleanCmd)
 }
 
@@ -27,21 +45,77 @@ func runClean(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("not in a git repository: %w", err)
 	}
 
-	e := newEnv(gitClient, repoRoot)
+	lk, err := acquireLock(gitClient)
+	if err != nil {
+		return err
+	}
+	defer lk.Release()
+
+	e, err := newEnv(gitClient, repoRoot, config.Load(repoRoot))
+	if err != nil {
+		return err
+	}
+	e.archiveStashes = archiveStashes
+	if cleanInteractive {
+		e.choose = func(labels []string) ([]bool, error) {
+			return chooseTerminal(os.Stdin, os.Stderr, labels)
+		}
+	}
+	if dryRun {
+		p := e.startDryRun()
+		if err := e.doClean(); err != nil {
+			return err
+		}
+		return p.write(e.stdout, e.jsonOut)
+	}
 	return e.doClean()
 }
 
 func (e *env) doClean() error {
-	result, err := cycle.FindRecyclable(e.deps)
+	result, err := e.findRecyclable()
 	if err != nil {
 		return err
 	}
 
+	for _, s := range result.Skipped {
+		if s.Reason == "unpushed" {
+			e.deps.Logf("⚠️  Keeping %s: %s never pushed", s.Branch, s.Detail)
+		}
+	}
+
 	if len(result.Recyclable) == 0 {
+		e.pruneArchives()
 		e.deps.Logf("✨ No worktrees to clean")
 		return nil
 	}
 
+	if e.choose != nil {
+		// Offer the worktrees in a stable order, by branch number.
+		names := e.deps.Names()
+		sort.Slice(result.Recyclable, func(i, j int) bool {
+			return names.Num(result.Recyclable[i].Branch) < names.Num(result.Recyclable[j].Branch)
+		})
+		selected, err := e.choose(e.cleanLabels(result.Recyclable))
+		if errors.Is(err, errCancelled) {
+			e.deps.Logf("Cancelled, nothing removed")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var chosen []cycle.Recyclable
+		for i, r := range result.Recyclable {
+			if selected[i] {
+				chosen = append(chosen, r)
+			}
+		}
+		if len(chosen) == 0 {
+			e.deps.Logf("✨ Nothing selected")
+			return nil
+		}
+		result.Recyclable = chosen
+	}
+
 	var branches []string
 	for _, r := range result.Recyclable {
 		branches = append(branches, r.Branch)
@@ -49,15 +123,23 @@ func (e *env) doClean() error {
 	e.deps.Logf("🧹 Cleaning: %v", branches)
 
 	for _, r := range result.Recyclable {
-		if err := e.runWt("remove", "-y", r.Branch); err != nil {
+		if err := e.hook(hooks.PreRemove, r.Branch, r.Path); err != nil {
+			e.deps.Logf("warning: keeping %s: %v", r.Branch, err)
+			continue
+		}
+		if err := e.worktrees().Remove(r.Branch, r.Path); err != nil {
 			e.deps.Logf("warning: failed to remove worktree %s: %v", r.Branch, err)
 			continue
 		}
+		if !e.archiveBranch(r) {
+			continue
+		}
 		if _, err := e.deps.Git.Run("branch", "-D", r.Branch); err != nil {
 			e.deps.Logf("warning: failed to delete branch %s: %v", r.Branch, err)
 		}
 	}
 
+	e.pruneArchives()
 	e.deps.Logf("✅ Done")
 	return nil
 }
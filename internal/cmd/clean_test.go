@@ -4,6 +4,13 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/cycle"
+	"github.com/sestinj/wt-cycle/internal/forge"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/hooks"
+	"github.com/sestinj/wt-cycle/internal/worktree"
 )
 
 func TestDoClean_HappyPath(t *testing.T) {
@@ -209,3 +216,258 @@ func TestDoClean_FindRecyclableError(t *testing.T) {
 		t.Errorf("error = %q, want it to mention 'merged'", err)
 	}
 }
+
+func TestDoClean_NativeBackend(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-42"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-42\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.backend = &worktree.Native{Git: g}
+
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.runCalls) != 2 {
+		t.Fatalf("expected 2 git Run calls, got %d: %v", len(g.runCalls), g.runCalls)
+	}
+	assertArgs(t, g.runCalls[0], "worktree", "remove", dir)
+	assertArgs(t, g.runCalls[1], "branch", "-D", "wt-42")
+}
+
+func TestDoClean_PreRemoveHookFails_Keeps(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1", "wt-2"},
+		wtPorcelain: fmt.Sprintf(
+			"worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\nworktree %s\nHEAD def\nbranch refs/heads/wt-2\n\n",
+			dir1, dir2,
+		),
+		cleanPaths: map[string]bool{dir1: true, dir2: true},
+		repoRoot:   "/repo",
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.backend = &worktree.Native{Git: g}
+	e.runHook = func(hc hooks.Context) error {
+		if hc.Action != hooks.PreRemove {
+			t.Errorf("unexpected hook %s", hc.Action)
+		}
+		if hc.Branch == "wt-1" {
+			return fmt.Errorf("pre-remove hook failed: exit status 1")
+		}
+		return nil
+	}
+
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.runCalls) != 2 {
+		t.Fatalf("expected 2 git Run calls, got %d: %v", len(g.runCalls), g.runCalls)
+	}
+	assertArgs(t, g.runCalls[0], "worktree", "remove", dir2)
+	assertArgs(t, g.runCalls[1], "branch", "-D", "wt-2")
+}
+
+func TestDoClean_ArchivesClosedUnmerged(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-5\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+		shas:          map[string]string{"refs/heads/wt-5": "abc123"},
+	}
+	gh := &mockGH{prs: []forge.PR{{Branch: "wt-5", State: forge.StateClosed, HeadSHA: "abc"}}}
+
+	e, _ := testEnv(t, g, gh)
+	e.backend = &worktree.Native{Git: g}
+	e.deps.ClosedPRPolicy = cycle.PolicyArchive
+
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.updatedRefs) != 1 {
+		t.Fatalf("expected 1 archive ref, got %v", g.updatedRefs)
+	}
+	for ref, sha := range g.updatedRefs {
+		if !strings.HasPrefix(ref, "refs/wt-cycle/pinned/wt-5/") || sha != "abc123" {
+			t.Errorf("archived %s -> %s", ref, sha)
+		}
+	}
+	assertArgs(t, g.runCalls[len(g.runCalls)-1], "branch", "-D", "wt-5")
+}
+
+func TestDoClean_ArchiveFails_KeepsBranch(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-5\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+		updateRefErr:  fmt.Errorf("cannot lock ref"),
+	}
+	gh := &mockGH{prs: []forge.PR{{Branch: "wt-5", State: forge.StateClosed, HeadSHA: "abc"}}}
+
+	e, _ := testEnv(t, g, gh)
+	e.backend = &worktree.Native{Git: g}
+	e.deps.ClosedPRPolicy = cycle.PolicyArchive
+
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, call := range g.runCalls {
+		if call[0] == "branch" {
+			t.Errorf("branch should be kept when archiving fails, got %v", call)
+		}
+	}
+}
+
+func TestDoClean_KeepsUnpushed(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-4\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+		unpushed:      map[string]int{"wt-4": 2},
+	}
+	gh := &mockGH{branches: []string{"wt-4"}}
+
+	e, _ := testEnv(t, g, gh)
+	var logs []string
+	e.deps.Logf = func(format string, a ...interface{}) { logs = append(logs, fmt.Sprintf(format, a...)) }
+	e.runWt = func(args ...string) error {
+		t.Fatalf("wt should not be called, got %v", args)
+		return nil
+	}
+
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.runCalls) != 0 {
+		t.Errorf("expected no git Run calls, got %v", g.runCalls)
+	}
+	if !strings.Contains(strings.Join(logs, "\n"), "Keeping wt-4: 2 commit(s) never pushed") {
+		t.Errorf("expected keep message, got %v", logs)
+	}
+}
+
+func TestDoClean_ArchiveStashes(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-2"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-2\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+		stashList:     "stash@{0}\taaa\taaa-base aaa-index\tWIP on wt-2: abc wip\n",
+	}
+	g.runFn = func(args []string) (string, error) {
+		if args[0] == "stash" && args[1] == "drop" {
+			g.stashList = ""
+		}
+		return "", nil
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.backend = &worktree.Native{Git: g}
+	e.archiveStashes = true
+
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stashRefs int
+	for ref, sha := range g.updatedRefs {
+		if strings.HasPrefix(ref, "refs/wt-cycle/stash/wt-2/") {
+			stashRefs++
+			if sha != "aaa" {
+				t.Errorf("archived %s -> %s", ref, sha)
+			}
+		}
+	}
+	if stashRefs != 1 {
+		t.Fatalf("expected the stash to be archived, got %v", g.updatedRefs)
+	}
+	if len(g.runCalls) != 3 {
+		t.Fatalf("expected drop, remove and branch -D, got %v", g.runCalls)
+	}
+	assertArgs(t, g.runCalls[0], "stash", "drop", "-q", "stash@{0}")
+	assertArgs(t, g.runCalls[1], "worktree", "remove", dir)
+	assertArgs(t, g.runCalls[2], "branch", "-D", "wt-2")
+}
+
+func TestDoClean_ArchivesDeletedBranches(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-2"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-2\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+		shas:          map[string]string{"refs/heads/wt-2": "def456"},
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.backend = &worktree.Native{Git: g}
+
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.updatedRefs) != 1 {
+		t.Fatalf("expected 1 archive ref, got %v", g.updatedRefs)
+	}
+	for ref, sha := range g.updatedRefs {
+		if !strings.HasPrefix(ref, "refs/wt-cycle/archive/wt-2/") || sha != "def456" {
+			t.Errorf("archived %s -> %s", ref, sha)
+		}
+	}
+	assertArgs(t, g.runCalls[len(g.runCalls)-1], "branch", "-D", "wt-2")
+}
+
+func TestDoClean_PrunesOldArchives(t *testing.T) {
+	old := time.Now().Add(-40 * 24 * time.Hour).Unix()
+	recent := time.Now().Add(-time.Hour).Unix()
+
+	g := &mockGit{
+		currentBranch: "main",
+		repoRoot:      t.TempDir(),
+		refList: []gitpkg.Ref{
+			{Name: fmt.Sprintf("refs/wt-cycle/archive/wt-1/%d", old), SHA: "a"},
+			{Name: fmt.Sprintf("refs/wt-cycle/archive/wt-2/%d", recent), SHA: "b"},
+			{Name: fmt.Sprintf("refs/wt-cycle/pinned/wt-3/%d", old), SHA: "c"},
+		},
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.archiveRetention = 30 * 24 * time.Hour
+
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.deletedRefs) != 1 || g.deletedRefs[0] != g.refList[0].Name {
+		t.Errorf("deleted refs = %v, want only the old unpinned archive", g.deletedRefs)
+	}
+}
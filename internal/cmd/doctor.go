@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sestinj/wt-cycle/internal/config"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/gitea"
+	ghpkg "github.com/sestinj/wt-cycle/internal/github"
+	"github.com/sestinj/wt-cycle/internal/gitlab"
+	"github.com/sestinj/wt-cycle/internal/lock"
+	"github.com/sestinj/wt-cycle/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the environment and repository state",
+	Long: "Checks git, the config files, worktrunk, forge authentication, the repo lock and worktree metadata, " +
+		"reporting pass, warn or fail for each. --fix removes a stale lock directory left by older versions and " +
+		"prunes stale worktree entries. Exits non-zero if any check fails.",
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+var doctorFix bool
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "apply safe repairs")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// Check statuses reported by doctor.
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+type checkResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Fixed  bool   `json:"fixed,omitempty"`
+}
+
+// doctorCheck is one diagnosis with an optional safe repair.
+type doctorCheck struct {
+	name string
+	run  func() (status, detail string)
+	fix  func() error
+}
+
+// doctor holds what the checks inspect. Tests construct it directly.
+type doctor struct {
+	git         gitpkg.Client
+	repoRoot    string     // empty outside a repository
+	configPaths []string   // global first, then repo
+	lock        *lock.Lock // nil if it could not be located; see lockErr
+	lockErr     error
+	lookPath    func(file string) (string, error)
+	run         func(name string, args ...string) error
+	stdout      io.Writer
+	jsonOut     bool
+
+	cfg    config.Config
+	env    *env
+	envErr error
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	gitClient := gitpkg.NewExecClient()
+	d := &doctor{
+		git:         gitClient,
+		configPaths: []string{config.GlobalPath()},
+		lookPath:    exec.LookPath,
+		run: func(name string, args ...string) error {
+			return exec.Command(name, args...).Run()
+		},
+		stdout:  os.Stdout,
+		jsonOut: jsonOut,
+	}
+	if root, err := gitClient.RepoRoot(); err == nil {
+		d.repoRoot = root
+		d.configPaths = append(d.configPaths, filepath.Join(root, config.RepoFileName))
+		d.lock, d.lockErr = repoLock(gitClient)
+	}
+	return d.doDoctor(doctorFix)
+}
+
+func (d *doctor) doDoctor(fix bool) error {
+	// The same layering as config.Load, over the paths being checked.
+	d.cfg = config.Config{}
+	for _, path := range d.configPaths {
+		c, _ := config.ReadFile(path)
+		if filepath.Base(path) == config.RepoFileName {
+			c, _ = c.DropGlobalOnly()
+		}
+		d.cfg = d.cfg.Merge(c)
+	}
+	if d.repoRoot != "" {
+		d.env, d.envErr = newEnv(d.git, d.repoRoot, d.cfg)
+		if d.env != nil {
+			// Repairs go through the journaled client.
+			d.git = d.env.deps.Git
+		}
+	}
+
+	checks := []doctorCheck{
+		{name: "git", run: d.checkGit},
+		{name: "config", run: d.checkConfig},
+	}
+	if d.repoRoot != "" {
+		checks = append(checks,
+			doctorCheck{name: "worktrunk", run: d.checkWorktrunk},
+			doctorCheck{name: "forge", run: d.checkForge},
+			doctorCheck{name: "lock", run: d.checkLock, fix: d.fixLock},
+			doctorCheck{name: "worktrees", run: d.checkWorktrees, fix: d.fixWorktrees},
+		)
+	}
+
+	var results []checkResult
+	failed := 0
+	for _, c := range checks {
+		r := checkResult{Name: c.name}
+		r.Status, r.Detail = c.run()
+		if fix && r.Status != checkPass && c.fix != nil {
+			if err := c.fix(); err != nil {
+				r.Detail += fmt.Sprintf(" (fix failed: %v)", err)
+			} else {
+				r.Status, r.Detail = c.run()
+				r.Fixed = r.Status == checkPass
+			}
+		}
+		if r.Status == checkFail {
+			failed++
+		}
+		results = append(results, r)
+	}
+
+	if d.jsonOut {
+		enc := json.NewEncoder(d.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(d.stdout, 0, 4, 2, ' ', 0)
+		for _, r := range results {
+			detail := r.Detail
+			if r.Fixed {
+				detail += " (fixed)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Status, r.Name, detail)
+		}
+		w.Flush()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+func (d *doctor) checkGit() (string, string) {
+	if _, err := d.lookPath("git"); err != nil {
+		return checkFail, "git is not on PATH"
+	}
+	if d.repoRoot == "" {
+		return checkFail, "not in a git repository"
+	}
+	return checkPass, "repository at " + d.repoRoot
+}
+
+// checkConfig parses each config file on its own, since config.Load
+// silently ignores broken ones, then validates the merged result.
+func (d *doctor) checkConfig() (string, string) {
+	var loaded, problems, unknown []string
+	for _, path := range d.configPaths {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if _, err := config.ReadFile(path); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		loaded = append(loaded, path)
+
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		var c config.Config
+		if err := dec.Decode(&c); err != nil {
+			unknown = append(unknown, fmt.Sprintf("%s: %v", path, err))
+		}
+		if _, dropped := c.DropGlobalOnly(); filepath.Base(path) == config.RepoFileName && len(dropped) > 0 {
+			unknown = append(unknown, fmt.Sprintf("%s: %s ignored, set only in %s", path, strings.Join(dropped, ", "), config.GlobalPath()))
+		}
+	}
+	switch {
+	case len(problems) > 0:
+		return checkFail, strings.Join(problems, "; ") + " (the file is being ignored)"
+	case d.envErr != nil:
+		return checkFail, d.envErr.Error()
+	case len(unknown) > 0:
+		return checkWarn, strings.Join(unknown, "; ")
+	case len(loaded) == 0:
+		return checkPass, "no config files, using defaults"
+	}
+	return checkPass, "loaded " + strings.Join(loaded, ", ")
+}
+
+func (d *doctor) checkWorktrunk() (string, string) {
+	_, err := d.lookPath("wt")
+	switch {
+	case d.cfg.Backend == worktree.NameGit:
+		return checkPass, "not used (backend is git)"
+	case err == nil:
+		return checkPass, "wt found"
+	case d.cfg.Backend == worktree.NameWorktrunk:
+		return checkFail, "backend is worktrunk but wt is not on PATH"
+	}
+	return checkPass, "wt not found, using plain git worktrees"
+}
+
+func (d *doctor) checkForge() (string, string) {
+	if d.env == nil {
+		return checkWarn, "skipped: config is invalid"
+	}
+	switch p := d.env.deps.Forge.(type) {
+	case *ghpkg.GHClient:
+		if _, err := d.lookPath("gh"); err != nil {
+			return checkWarn, "gh is not on PATH; merges are only detected through git"
+		}
+		if err := d.run("gh", "auth", "status"); err != nil {
+			return checkWarn, "gh is not authenticated; run `gh auth login`"
+		}
+		return checkPass, "gh is authenticated"
+	case *ghpkg.RESTClient:
+		return tokenCheck("GitHub API", p.Token, "GITHUB_TOKEN or GH_TOKEN")
+	case *gitlab.Client:
+		return tokenCheck("GitLab API", p.Token, "GITLAB_TOKEN")
+	case *gitea.Client:
+		return tokenCheck("Gitea API", p.Token, "GITEA_TOKEN or FORGEJO_TOKEN")
+	}
+	return checkPass, "configured"
+}
+
+func tokenCheck(api, token, vars string) (string, string) {
+	if token == "" {
+		return checkWarn, fmt.Sprintf("%s without a token; set %s or forge.token for private repos and higher rate limits", api, vars)
+	}
+	return checkPass, api + " with a token"
+}
+
+func (d *doctor) checkLock() (string, string) {
+	if d.lock == nil {
+		return checkFail, fmt.Sprintf("cannot locate the lock: %v", d.lockErr)
+	}
+	if lg := d.lock.Legacy(); lg != nil {
+		if lg.Stale {
+			return checkWarn, fmt.Sprintf("stale lock directory %s from an older wt-cycle", lg.Path)
+		}
+		if lg.PID <= 0 {
+			return checkWarn, fmt.Sprintf("an older wt-cycle is just taking %s", lg.Path)
+		}
+		return checkWarn, fmt.Sprintf("older wt-cycle process %d holds %s", lg.PID, lg.Path)
+	}
+	s, err := d.lock.Status()
+	if err != nil {
+		return checkFail, fmt.Sprintf("reading %s: %v", s.Path, err)
+	}
+	if !s.Held {
+		return checkPass, "free"
+	}
+	detail := "held"
+	if s.Holder != nil {
+		detail = "held by " + s.Holder.Describe()
+	}
+	if s.Waiting > 0 {
+		detail += fmt.Sprintf(", %d waiting", s.Waiting)
+	}
+	return checkPass, detail
+}
+
+func (d *doctor) fixLock() error {
+	if d.lock == nil {
+		return d.lockErr
+	}
+	lg := d.lock.Legacy()
+	if lg == nil {
+		return nil
+	}
+	if !lg.Stale && lg.PID <= 0 {
+		return fmt.Errorf("an older wt-cycle is just taking it")
+	}
+	if !lg.Stale {
+		return fmt.Errorf("process %d still holds it", lg.PID)
+	}
+	return os.RemoveAll(lg.Path)
+}
+
+func (d *doctor) checkWorktrees() (string, string) {
+	out, err := d.git.WorktreeListPorcelain()
+	if err != nil {
+		return checkFail, fmt.Sprintf("listing worktrees: %v", err)
+	}
+	var stale []string
+	for _, wt := range gitpkg.ParseWorktreeList(out) {
+		if wt.Prunable {
+			stale = append(stale, wt.Path)
+		}
+	}
+	if len(stale) > 0 {
+		return checkWarn, fmt.Sprintf("%d stale worktree entr(ies) whose directory is gone: %s", len(stale), strings.Join(stale, ", "))
+	}
+	return checkPass, "metadata is consistent"
+}
+
+func (d *doctor) fixWorktrees() error {
+	_, err := d.git.Run("worktree", "prune")
+	return err
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/config"
+	"github.com/sestinj/wt-cycle/internal/lock"
+)
+
+// testDoctor returns a doctor for a healthy repo in a temp dir, with every
+// tool on PATH and gh authenticated.
+func testDoctor(t *testing.T, g *mockGit) (*doctor, *strings.Builder) {
+	t.Helper()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	if g.repoRoot == "" {
+		g.repoRoot = t.TempDir()
+	}
+	var stdout strings.Builder
+	return &doctor{
+		git:         g,
+		repoRoot:    g.repoRoot,
+		configPaths: []string{filepath.Join(g.repoRoot, config.RepoFileName)},
+		lock:        lock.New(g.repoRoot),
+		lookPath:    func(file string) (string, error) { return "/usr/bin/" + file, nil },
+		run:         func(string, ...string) error { return nil },
+		stdout:      &stdout,
+		jsonOut:     true,
+	}, &stdout
+}
+
+func doctorResults(t *testing.T, stdout *strings.Builder) map[string]checkResult {
+	t.Helper()
+	var results []checkResult
+	if err := json.Unmarshal([]byte(stdout.String()), &results); err != nil {
+		t.Fatalf("parsing %q: %v", stdout.String(), err)
+	}
+	byName := make(map[string]checkResult)
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+	return byName
+}
+
+func TestDoctor_Healthy(t *testing.T) {
+	d, stdout := testDoctor(t, &mockGit{})
+
+	if err := d.doDoctor(false); err != nil {
+		t.Fatal(err)
+	}
+	results := doctorResults(t, stdout)
+	for _, name := range []string{"git", "config", "worktrunk", "forge", "lock", "worktrees"} {
+		if r, ok := results[name]; !ok || r.Status != checkPass {
+			t.Errorf("%s = %+v, want pass", name, r)
+		}
+	}
+}
+
+func TestDoctor_MalformedConfig(t *testing.T) {
+	d, stdout := testDoctor(t, &mockGit{})
+	os.WriteFile(d.configPaths[0], []byte(`{"naming": "wt-{n}",}`), 0644)
+
+	if err := d.doDoctor(false); err == nil {
+		t.Fatal("expected doctor to fail")
+	}
+	if r := doctorResults(t, stdout)["config"]; r.Status != checkFail || !strings.Contains(r.Detail, config.RepoFileName) {
+		t.Errorf("config = %+v", r)
+	}
+}
+
+func TestDoctor_InvalidAndUnknownConfig(t *testing.T) {
+	d, stdout := testDoctor(t, &mockGit{})
+	os.WriteFile(d.configPaths[0], []byte(`{"closed_pr_polcy": "keep"}`), 0644)
+
+	if err := d.doDoctor(false); err != nil {
+		t.Fatal(err)
+	}
+	if r := doctorResults(t, stdout)["config"]; r.Status != checkWarn || !strings.Contains(r.Detail, "closed_pr_polcy") {
+		t.Errorf("config = %+v", r)
+	}
+
+	stdout.Reset()
+	os.WriteFile(d.configPaths[0], []byte(`{"backend": "jj"}`), 0644)
+	if err := d.doDoctor(false); err == nil {
+		t.Fatal("expected doctor to fail")
+	}
+	if r := doctorResults(t, stdout)["config"]; r.Status != checkFail || !strings.Contains(r.Detail, "jj") {
+		t.Errorf("config = %+v", r)
+	}
+}
+
+func TestDoctor_RepoConfigSetsForgeToken(t *testing.T) {
+	d, stdout := testDoctor(t, &mockGit{})
+	os.WriteFile(d.configPaths[0], []byte(`{"forge": {"host": "evil.example.com", "token": "t"}}`), 0644)
+
+	if err := d.doDoctor(false); err != nil {
+		t.Fatal(err)
+	}
+	if r := doctorResults(t, stdout)["config"]; r.Status != checkWarn || !strings.Contains(r.Detail, "forge.host, forge.token ignored") {
+		t.Errorf("config = %+v", r)
+	}
+	if d.cfg.Forge.Host != "" || d.cfg.Forge.Token != "" {
+		t.Errorf("forge config taken from the repo file: %+v", d.cfg.Forge)
+	}
+}
+
+func TestDoctor_ToolsMissing(t *testing.T) {
+	d, stdout := testDoctor(t, &mockGit{})
+	os.WriteFile(d.configPaths[0], []byte(`{"backend": "worktrunk"}`), 0644)
+	d.lookPath = func(file string) (string, error) {
+		if file == "git" {
+			return "/usr/bin/git", nil
+		}
+		return "", fmt.Errorf("%s: not found", file)
+	}
+
+	if err := d.doDoctor(false); err == nil {
+		t.Fatal("expected doctor to fail")
+	}
+	results := doctorResults(t, stdout)
+	if r := results["worktrunk"]; r.Status != checkFail {
+		t.Errorf("worktrunk = %+v", r)
+	}
+	if r := results["forge"]; r.Status != checkWarn {
+		t.Errorf("forge = %+v", r)
+	}
+}
+
+func TestDoctor_GHUnauthenticated(t *testing.T) {
+	d, stdout := testDoctor(t, &mockGit{})
+	d.run = func(name string, args ...string) error { return fmt.Errorf("exit status 1") }
+
+	if err := d.doDoctor(false); err != nil {
+		t.Fatal(err)
+	}
+	if r := doctorResults(t, stdout)["forge"]; r.Status != checkWarn || !strings.Contains(r.Detail, "gh auth login") {
+		t.Errorf("forge = %+v", r)
+	}
+}
+
+func TestDoctor_FixLegacyLockAndPrunable(t *testing.T) {
+	g := &mockGit{}
+	d, stdout := testDoctor(t, g)
+	g.wtPorcelain = fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/main\n\n"+
+		"worktree /gone/repo.wt-1\nHEAD def\nbranch refs/heads/wt-1\nprunable gitdir file points to non-existent location\n\n", g.repoRoot)
+	g.runFn = func(args []string) (string, error) {
+		if len(args) == 2 && args[0] == "worktree" && args[1] == "prune" {
+			g.wtPorcelain = fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/main\n\n", g.repoRoot)
+		}
+		return "", nil
+	}
+	// A PID above pid_max can never be alive.
+	legacy := legacyLockDir(t, d, 999999999)
+
+	if err := d.doDoctor(false); err != nil {
+		t.Fatal(err)
+	}
+	results := doctorResults(t, stdout)
+	if r := results["lock"]; r.Status != checkWarn || !strings.Contains(r.Detail, legacy) {
+		t.Errorf("lock = %+v", r)
+	}
+	if r := results["worktrees"]; r.Status != checkWarn || !strings.Contains(r.Detail, "/gone/repo.wt-1") {
+		t.Errorf("worktrees = %+v", r)
+	}
+
+	stdout.Reset()
+	if err := d.doDoctor(true); err != nil {
+		t.Fatal(err)
+	}
+	results = doctorResults(t, stdout)
+	for _, name := range []string{"lock", "worktrees"} {
+		if r := results[name]; r.Status != checkPass || !r.Fixed {
+			t.Errorf("%s = %+v, want fixed", name, r)
+		}
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Error("legacy lock directory should be removed")
+	}
+}
+
+// legacyLockDir creates the lock directory an older wt-cycle held as pid.
+func legacyLockDir(t *testing.T, d *doctor, pid int) string {
+	t.Helper()
+	dir := "/tmp/wt-cycle-lock-" + strings.TrimSuffix(filepath.Base(d.lock.Path()), ".lock")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	os.WriteFile(filepath.Join(dir, "pid"), []byte(strconv.Itoa(pid)), 0644)
+	return dir
+}
+
+func TestDoctor_LegacyLockHeldByLiveProcess(t *testing.T) {
+	d, stdout := testDoctor(t, &mockGit{})
+	legacy := legacyLockDir(t, d, os.Getpid())
+	// However long it has been held, a live holder keeps it.
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(legacy, old, old)
+
+	if err := d.doDoctor(true); err != nil {
+		t.Fatal(err)
+	}
+	if r := doctorResults(t, stdout)["lock"]; r.Status != checkWarn || r.Fixed || !strings.Contains(r.Detail, "fix failed") {
+		t.Errorf("lock = %+v, want an unfixed warning", r)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Error("a lock directory held by a live process must not be removed")
+	}
+}
+
+func TestDoctor_LockUnavailable(t *testing.T) {
+	d, stdout := testDoctor(t, &mockGit{})
+	d.lock, d.lockErr = nil, fmt.Errorf("locating main worktree: no worktrees")
+
+	if err := d.doDoctor(true); err == nil {
+		t.Error("expected doctor to report a failure")
+	}
+	if r := doctorResults(t, stdout)["lock"]; r.Status != checkFail || !strings.Contains(r.Detail, "no worktrees") {
+		t.Errorf("lock = %+v, want a failure naming the error", r)
+	}
+}
+
+func TestDoctor_LockHeld(t *testing.T) {
+	d, stdout := testDoctor(t, &mockGit{})
+	if err := d.lock.Acquire(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	defer d.lock.Release()
+
+	if err := d.doDoctor(false); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("held by PID %d", os.Getpid())
+	if r := doctorResults(t, stdout)["lock"]; r.Status != checkPass || !strings.Contains(r.Detail, want) {
+		t.Errorf("lock = %+v, want pass %q", r, want)
+	}
+}
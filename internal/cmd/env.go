@@ -5,11 +5,23 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"syscall"
+	"time"
 
+	"github.com/sestinj/wt-cycle/internal/archive"
 	"github.com/sestinj/wt-cycle/internal/cache"
+	"github.com/sestinj/wt-cycle/internal/config"
 	"github.com/sestinj/wt-cycle/internal/cycle"
+	"github.com/sestinj/wt-cycle/internal/forge"
 	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/gitea"
 	ghpkg "github.com/sestinj/wt-cycle/internal/github"
+	"github.com/sestinj/wt-cycle/internal/gitlab"
+	"github.com/sestinj/wt-cycle/internal/hooks"
+	"github.com/sestinj/wt-cycle/internal/journal"
+	"github.com/sestinj/wt-cycle/internal/lease"
+	"github.com/sestinj/wt-cycle/internal/procs"
+	"github.com/sestinj/wt-cycle/internal/worktree"
 )
 
 // env bundles dependencies for command execution.
@@ -18,34 +30,368 @@ type env struct {
 	repoRoot string
 	deps     *cycle.Deps
 	runWt    func(args ...string) error
+	backend  worktree.Backend // nil means worktrunk via runWt
 	chdir    func(path string) error
 	stdout   io.Writer
 	jsonOut  bool
+
+	claimOwner string // when set, next leases the worktree it hands out
+	claimTTL   time.Duration
+	claimPID   int // when non-zero, the lease also ends when this process exits
+
+	poolSize int                        // configured warm pool size
+	spawn    func(args ...string) error // starts a detached wt-cycle subcommand; nil disables
+
+	runHook func(hooks.Context) error // nil means no hooks
+
+	archiveStashes bool // convert stashes on candidate branches to refs instead of skipping
+
+	archiveRetention time.Duration // clean prunes unpinned archives older than this; zero disables
+
+	journal *journal.Journal // records mutations for history and undo; nil disables
+
+	plan *planner // set by startDryRun; mutations are recorded here instead of run
+
+	choose func(labels []string) ([]bool, error) // when set, clean asks which worktrees to remove
+
+	runCommand func(dir string, argv []string) error // runs exec's command with our stdio
 }
 
-func newEnv(gitClient gitpkg.Client, repoRoot string) *env {
+func newEnv(gitClient gitpkg.Client, repoRoot string, cfg config.Config) (*env, error) {
 	logf := func(format string, a ...interface{}) {
 		fmt.Fprintf(os.Stderr, format+"\n", a...)
 	}
-	return &env{
+
+	// Leases and the journal are shared by all worktrees of the repo, so
+	// key them by the main worktree rather than whichever one we're in.
+	mainRoot, err := cycle.MainWorktree(&cycle.Deps{Git: gitClient})
+	if err != nil {
+		return nil, err
+	}
+	jr := journal.New(mainRoot, invocation)
+	journaled := &journal.Git{Client: gitClient, Journal: jr}
+	gitClient = journaled
+
+	remote, baseBranch := resolveBase(gitClient, cfg)
+	naming := gitpkg.DefaultNaming()
+	if cfg.Naming != "" {
+		if naming, err = gitpkg.ParseNaming(cfg.Naming); err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+	}
+	if cfg.PathTemplate != "" {
+		if err := cycle.ValidatePathTemplate(cfg.PathTemplate); err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+	}
+	backend, err := selectBackend(gitClient, cfg.Backend)
+	if err != nil {
+		return nil, err
+	}
+	processes, err := selectInUseCheck(cfg.InUseCheck)
+	if err != nil {
+		return nil, err
+	}
+	hookRunner, err := newHookRunner(cfg.Hooks)
+	if err != nil {
+		return nil, err
+	}
+	hookRunner.Logf = logf
+	prProvider, err := selectForge(gitClient, remote, cfg.Forge)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.ClosedPRPolicy {
+	case "", cycle.PolicyRecycle, cycle.PolicyArchive, cycle.PolicyKeep:
+	default:
+		return nil, fmt.Errorf("invalid config: unknown closed_pr_policy %q (want recycle, archive or keep)", cfg.ClosedPRPolicy)
+	}
+	retention := archive.DefaultRetention
+	if days := cfg.Archive.RetentionDays; days > 0 {
+		retention = time.Duration(days) * 24 * time.Hour
+	} else if days < 0 {
+		retention = 0
+	}
+	e := &env{
 		repoRoot: repoRoot,
+		backend:  backend,
 		deps: &cycle.Deps{
-			Git:     gitClient,
-			GitHub:  ghpkg.NewGHClient(),
-			Cache:   cache.New(repoRoot),
-			NoCache: noCache,
-			Verbose: verbose,
-			Logf:    logf,
+			Git:            gitClient,
+			Forge:          prProvider,
+			Cache:          cache.New(repoRoot),
+			Remote:         remote,
+			BaseBranch:     baseBranch,
+			Naming:         naming,
+			PathTemplate:   cfg.PathTemplate,
+			Processes:      processes,
+			ClosedPRPolicy: cfg.ClosedPRPolicy,
+			NoCache:        noCache,
+			Verbose:        verbose,
+			Logf:           logf,
 		},
-		runWt: func(args ...string) error {
+		runWt: journaled.Wt(func(args ...string) error {
 			c := exec.Command("wt", args...)
 			c.Stdout = os.Stderr
 			c.Stderr = os.Stderr
 			c.Stdin = os.Stdin
 			return c.Run()
-		},
-		chdir:   os.Chdir,
-		stdout:  os.Stdout,
-		jsonOut: jsonOut,
+		}),
+		chdir:    os.Chdir,
+		stdout:   os.Stdout,
+		jsonOut:  jsonOut,
+		poolSize: cfg.Pool.SizeOrZero(),
+		spawn:    spawnSelf,
+		runHook:  hookRunner.Run,
+
+		runCommand: runAttached,
+
+		archiveRetention: retention,
+		journal:          jr,
+	}
+	e.deps.Leases = lease.New(mainRoot)
+	return e, nil
+}
+
+// spawnSelf starts this binary with args in a new session, detached from
+// our stdio, and does not wait for it.
+func spawnSelf(args ...string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	c := exec.Command(exe, args...)
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Process.Release()
+}
+
+// newHookRunner builds a hook runner from config.
+func newHookRunner(cfg config.Hooks) (*hooks.Runner, error) {
+	r := &hooks.Runner{Commands: map[string]string{
+		hooks.PostCreate:  cfg.PostCreate,
+		hooks.PostRecycle: cfg.PostRecycle,
+		hooks.PreRemove:   cfg.PreRemove,
+	}}
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config: hooks.timeout: %w", err)
+		}
+		r.Timeout = d
+	}
+	return r, nil
+}
+
+// hook runs the action's hook for the worktree at path.
+func (e *env) hook(action, branch, path string) error {
+	if e.runHook == nil {
+		return nil
+	}
+	num := -1
+	if branch != "" {
+		num = e.deps.Names().Num(branch)
+	}
+	return e.runHook(hooks.Context{Action: action, Branch: branch, Path: path, Num: num})
+}
+
+// findRecyclable runs cycle.FindRecyclable. With archiveStashes set,
+// stashes holding back otherwise recyclable branches are archived and
+// dropped, and the search is repeated.
+func (e *env) findRecyclable() (*cycle.FindResult, error) {
+	result, err := cycle.FindRecyclable(e.deps)
+	if err != nil || !e.archiveStashes {
+		return result, err
+	}
+
+	var blocked []string
+	for _, s := range result.Skipped {
+		if s.Reason == "has-stash" {
+			blocked = append(blocked, s.Branch)
+		}
+	}
+	if len(blocked) == 0 {
+		return result, nil
+	}
+
+	byBranch, err := cycle.BranchStashes(e.deps)
+	if err != nil {
+		return nil, fmt.Errorf("listing stashes: %w", err)
+	}
+	var stashes []gitpkg.Stash
+	for _, b := range blocked {
+		stashes = append(stashes, byBranch[b]...)
+	}
+	refs, err := archive.SaveStashes(e.deps.Git, stashes, time.Now())
+	for _, ref := range refs {
+		e.deps.Logf("📦 Archived stash as %s", ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cycle.FindRecyclable(e.deps)
+}
+
+// archiveBranch saves the tip of r's branch so `restore` can bring it
+// back, pinning it when FindRecyclable asked for a permanent archive. It
+// reports whether the branch may now be deleted.
+func (e *env) archiveBranch(r cycle.Recyclable) bool {
+	save, why := archive.Save, ""
+	if r.Archive {
+		save, why = archive.Pin, " (PR closed unmerged)"
+	}
+	ref, err := save(e.deps.Git, r.Branch, time.Now())
+	if err != nil {
+		e.deps.Logf("warning: not deleting %s: %v", r.Branch, err)
+		return false
+	}
+	e.deps.Logf("📦 Archived %s%s as %s", r.Branch, why, ref)
+	return true
+}
+
+// pruneArchives deletes unpinned archives past the retention period.
+func (e *env) pruneArchives() {
+	if e.archiveRetention <= 0 {
+		return
+	}
+	pruned, err := archive.Prune(e.deps.Git, time.Now(), e.archiveRetention)
+	if len(pruned) > 0 {
+		e.deps.Logf("🗑️  Pruned %d archived branch(es) older than %s", len(pruned), e.archiveRetention)
+	}
+	if err != nil {
+		e.deps.Logf("warning: pruning archives: %v", err)
+	}
+}
+
+// worktrees returns the worktree backend, defaulting to worktrunk driven
+// through runWt.
+func (e *env) worktrees() worktree.Backend {
+	if e.backend != nil {
+		return e.backend
+	}
+	return &worktree.Worktrunk{Run: e.runWt}
+}
+
+// selectBackend maps the configured backend name to an implementation.
+// A nil result selects worktrunk.
+func selectBackend(gitClient gitpkg.Client, name string) (worktree.Backend, error) {
+	switch name {
+	case worktree.NameWorktrunk:
+		return nil, nil
+	case worktree.NameGit:
+		return &worktree.Native{Git: gitClient}, nil
+	case "":
+		if worktree.WorktrunkAvailable() {
+			return nil, nil
+		}
+		return &worktree.Native{Git: gitClient}, nil
+	default:
+		return nil, fmt.Errorf("invalid config: unknown backend %q (want %q or %q)", name, worktree.NameWorktrunk, worktree.NameGit)
+	}
+}
+
+// selectForge returns the pull request provider named in config, or
+// detects one from the forge host. GitHub repos use the gh CLI when it is
+// installed and the REST API otherwise.
+func selectForge(gitClient gitpkg.Client, remote string, cfg config.Forge) (forge.Provider, error) {
+	url, urlErr := gitClient.RemoteURL(remote)
+	host := cfg.Host
+	if host == "" && urlErr == nil {
+		host, _, _ = forge.ParseRemoteURL(url)
+	}
+
+	provider := cfg.Provider
+	if provider == "" {
+		provider = forge.Detect(host)
+		if provider == "" {
+			provider = forge.ProviderGH
+		} else if provider == forge.ProviderGitHub {
+			if _, err := exec.LookPath("gh"); err == nil {
+				provider = forge.ProviderGH
+			}
+		}
+	}
+	if provider == forge.ProviderGH {
+		return ghpkg.NewGHClient(), nil
+	}
+
+	if urlErr != nil {
+		return nil, fmt.Errorf("reading URL of remote %s: %w", remote, urlErr)
+	}
+	apiURL := cfg.APIURL
+	if apiURL == "" && cfg.Host != "" {
+		apiURL = "https://" + cfg.Host + apiPaths[provider]
+	}
+	switch provider {
+	case forge.ProviderGitHub:
+		c, err := ghpkg.NewRESTClient(url, apiURL)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Token != "" {
+			c.Token = cfg.Token
+		}
+		return c, nil
+	case forge.ProviderGitLab:
+		c, err := gitlab.NewClient(url, apiURL)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Token != "" {
+			c.Token = cfg.Token
+		}
+		return c, nil
+	case forge.ProviderGitea:
+		c, err := gitea.NewClient(url, apiURL)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Token != "" {
+			c.Token = cfg.Token
+		}
+		return c, nil
+	default:
+		return nil, fmt.Errorf("invalid config: unknown forge provider %q (want gh, github, gitlab or gitea)", cfg.Provider)
+	}
+}
+
+// apiPaths maps providers to their API root on a self-hosted instance.
+var apiPaths = map[string]string{
+	forge.ProviderGitHub: "/api/v3",
+	forge.ProviderGitLab: "/api/v4",
+	forge.ProviderGitea:  "/api/v1",
+}
+
+// selectInUseCheck maps the in_use_check setting to a process lister.
+// A nil result disables the check.
+func selectInUseCheck(mode string) (func() ([]procs.Process, error), error) {
+	switch mode {
+	case "", "cwd":
+		return func() ([]procs.Process, error) { return procs.List(false) }, nil
+	case "files":
+		return func() ([]procs.Process, error) { return procs.List(true) }, nil
+	case "off":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid config: unknown in_use_check %q (want cwd, files or off)", mode)
+	}
+}
+
+// resolveBase returns the remote and base branch to use, preferring config
+// and falling back to the remote's HEAD, then "main".
+func resolveBase(gitClient gitpkg.Client, cfg config.Config) (remote, branch string) {
+	remote = cfg.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	branch = cfg.BaseBranch
+	if branch == "" {
+		if b, err := gitClient.DefaultBranch(remote); err == nil && b != "" {
+			branch = b
+		} else {
+			branch = "main"
+		}
 	}
+	return remote, branch
 }
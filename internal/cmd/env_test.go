@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/config"
+	"github.com/sestinj/wt-cycle/internal/gitea"
+	ghpkg "github.com/sestinj/wt-cycle/internal/github"
+	"github.com/sestinj/wt-cycle/internal/gitlab"
+)
+
+func TestSelectForge(t *testing.T) {
+	g := &mockGit{remoteURL: "git@gitlab.example.com:group/widgets.git"}
+
+	p, err := selectForge(g, "origin", config.Forge{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*gitlab.Client); !ok {
+		t.Errorf("detected %T for a GitLab remote, want *gitlab.Client", p)
+	}
+
+	p, err = selectForge(g, "origin", config.Forge{Provider: "gh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*ghpkg.GHClient); !ok {
+		t.Errorf("got %T for provider gh, want *github.GHClient", p)
+	}
+
+	g.remoteURL = "https://github.com/acme/widgets.git"
+	p, err = selectForge(g, "origin", config.Forge{Provider: "github", APIURL: "https://ghe.example.com/api/v3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rest, ok := p.(*ghpkg.RESTClient)
+	if !ok || rest.APIURL != "https://ghe.example.com/api/v3" || rest.Owner != "acme" {
+		t.Errorf("got %+v for provider github", p)
+	}
+
+	if _, err := selectForge(g, "origin", config.Forge{Provider: "bitbucket"}); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestSelectForge_GiteaHostAndToken(t *testing.T) {
+	// A mirror cloned over an address that doesn't reveal the forge.
+	g := &mockGit{remoteURL: "git@mirror.internal:acme/widgets.git"}
+
+	p, err := selectForge(g, "origin", config.Forge{Host: "forgejo.example.com", Token: "cfg-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := p.(*gitea.Client)
+	if !ok {
+		t.Fatalf("detected %T, want *gitea.Client", p)
+	}
+	if c.APIURL != "https://forgejo.example.com/api/v1" || c.Token != "cfg-token" || c.Repo != "widgets" {
+		t.Errorf("client = %+v", c)
+	}
+}
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/config"
+	"github.com/sestinj/wt-cycle/internal/cycle"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/hooks"
+	"github.com/sestinj/wt-cycle/internal/lease"
+	"github.com/sestinj/wt-cycle/internal/lock"
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [--keep] -- <command> [args...]",
+	Short: "Run a command in a fresh worktree",
+	Long: "Gets a worktree the way next does, leases it to --owner for as long as the command runs, and runs " +
+		"the command there with stdio attached, exiting with its exit code. Afterward the lease is released and, " +
+		"unless --keep is given, a worktree left clean with no new commits is returned to the warm pool, or " +
+		"removed if the pool already has pool.size slots. Neither can be reversed with undo.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExec,
+}
+
+var execKeep bool
+
+// execLeaseTTL bounds an exec lease; it normally ends much sooner, when
+// the command returns or wt-cycle exits.
+const execLeaseTTL = 7 * 24 * time.Hour
+
+// execRelockTimeout bounds the wait for the repo lock after the command.
+var execRelockTimeout = lock.DefaultTimeout
+
+func init() {
+	execCmd.Flags().BoolVar(&execKeep, "keep", false, "keep the worktree on its branch even if it is unchanged")
+	execCmd.Flags().StringVar(&leaseOwner, "owner", defaultOwner(), "lease owner while the command runs")
+	rootCmd.AddCommand(execCmd)
+}
+
+// ExitError carries a command's exit status out of exec. It has already
+// been reported, so main should exit with Code without printing it.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return "exit status " + strconv.Itoa(e.Code)
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	gitClient := gitpkg.NewExecClient()
+
+	repoRoot, err := gitClient.RepoRoot()
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	lk, err := acquireLock(gitClient)
+	if err != nil {
+		return err
+	}
+	defer lk.Release()
+
+	e, err := newEnv(gitClient, repoRoot, config.Load(repoRoot))
+	if err != nil {
+		return err
+	}
+	e.claimOwner = leaseOwner
+	err = e.doExec(args, execKeep, lk)
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		cmd.SilenceErrors = true
+	}
+	return err
+}
+
+// doExec runs argv in a worktree from acquireWorktree and tidies up after
+// it. The repo lock lk, if given, is held on entry and released while the
+// command runs, so other wt-cycle invocations are not blocked meanwhile.
+func (e *env) doExec(argv []string, keep bool, lk *lock.Lock) error {
+	wt, err := e.acquireWorktree()
+	if err != nil {
+		return err
+	}
+	if e.deps.Leases != nil {
+		err := e.deps.Leases.Claim(lease.Lease{
+			Branch: wt.Branch,
+			Path:   wt.Path,
+			Owner:  e.claimOwner,
+			PID:    os.Getpid(),
+			TTL:    execLeaseTTL,
+		})
+		if err != nil {
+			return fmt.Errorf("claiming %s: %w", wt.Branch, err)
+		}
+	}
+
+	if lk != nil {
+		lk.Release()
+	}
+	e.deps.Logf("▶️  Running %s in %s", strings.Join(argv, " "), wt.Path)
+	runErr := e.runCommand(wt.Path, argv)
+	locked := true
+	if lk != nil {
+		if err := lk.Acquire(execRelockTimeout); err != nil {
+			e.deps.Logf("warning: acquiring lock: %v", err)
+			locked = false
+		}
+	}
+
+	if e.deps.Leases != nil {
+		if err := e.deps.Leases.Release(wt.Branch, e.claimOwner, false); err != nil {
+			e.deps.Logf("warning: releasing %s: %v", wt.Branch, err)
+		}
+	}
+	if locked {
+		e.finishExec(wt, keep)
+	} else {
+		// Tidying up without the lock would race other invocations.
+		e.deps.Logf("📌 Keeping %s at %s: the repo lock is busy", wt.Branch, wt.Path)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		code := exitErr.ExitCode()
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			code = 128 + int(ws.Signal())
+		}
+		return &ExitError{Code: code}
+	}
+	if runErr != nil {
+		return fmt.Errorf("running %s: %w", argv[0], runErr)
+	}
+	return nil
+}
+
+// finishExec returns wt to the pool, or removes it if the pool is full,
+// when the command left it unchanged and keep is not set; otherwise the
+// worktree stays on its branch.
+func (e *env) finishExec(wt gitpkg.Worktree, keep bool) {
+	if keep {
+		e.deps.Logf("📌 Keeping %s at %s", wt.Branch, wt.Path)
+		return
+	}
+	if why := e.changedSince(wt); why != "" {
+		e.deps.Logf("📌 Keeping %s at %s: %s", wt.Branch, wt.Path, why)
+		return
+	}
+	slots, err := cycle.PoolSlots(e.deps)
+	if err != nil {
+		e.deps.Logf("warning: could not return %s to the pool: %v", wt.Branch, err)
+		return
+	}
+	if len(slots) >= e.poolSize {
+		e.removeUnchanged(wt)
+		return
+	}
+	if err := e.returnToPool(wt, slots); err != nil {
+		e.deps.Logf("warning: could not return %s to the pool: %v", wt.Branch, err)
+	}
+}
+
+// removeUnchanged removes wt and its branch, which has nothing beyond the
+// base ref and so is not archived.
+func (e *env) removeUnchanged(wt gitpkg.Worktree) {
+	if err := e.hook(hooks.PreRemove, wt.Branch, wt.Path); err != nil {
+		e.deps.Logf("warning: keeping %s: %v", wt.Branch, err)
+		return
+	}
+	if err := e.worktrees().Remove(wt.Branch, wt.Path); err != nil {
+		e.deps.Logf("warning: failed to remove worktree %s: %v", wt.Branch, err)
+		return
+	}
+	if _, err := e.deps.Git.Run("branch", "-D", wt.Branch); err != nil {
+		e.deps.Logf("warning: could not delete branch %s: %v", wt.Branch, err)
+	}
+	e.deps.Logf("🗑️  Removed %s: the pool is full", wt.Branch)
+}
+
+// changedSince describes why wt is worth keeping, or returns "" if it is
+// still a clean checkout of its branch with nothing beyond the base ref.
+func (e *env) changedSince(wt gitpkg.Worktree) string {
+	head, err := e.deps.Git.Run("-C", wt.Path, "symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil || strings.TrimSpace(head) != wt.Branch {
+		return "it is no longer on " + wt.Branch
+	}
+	clean, err := e.deps.Git.IsClean(wt.Path)
+	if err != nil {
+		return fmt.Sprintf("checking for changes failed: %v", err)
+	}
+	if !clean {
+		return "it has uncommitted changes"
+	}
+	out, err := e.deps.Git.Run("rev-list", "--count", e.deps.BaseRef()+"..refs/heads/"+wt.Branch)
+	if err != nil {
+		return fmt.Sprintf("counting new commits failed: %v", err)
+	}
+	if n := strings.TrimSpace(out); n != "0" {
+		return n + " new commit(s)"
+	}
+	return ""
+}
+
+// returnToPool turns wt back into a warm pool slot: detached at the base
+// ref in the first slot directory not among slots, with its branch
+// deleted. The branch has nothing beyond the base ref, so it is not
+// archived.
+func (e *env) returnToPool(wt gitpkg.Worktree, slots []cycle.PoolSlot) error {
+	mainRoot, err := cycle.MainWorktree(e.deps)
+	if err != nil {
+		return fmt.Errorf("locating main worktree: %w", err)
+	}
+	taken := make(map[int]bool)
+	for _, s := range slots {
+		taken[s.Num] = true
+	}
+	var slotPath string
+	for k := 1; slotPath == ""; k++ {
+		path := e.deps.PoolLayout().Path(mainRoot, cycle.PoolSlotName(k))
+		if _, err := os.Stat(path); !taken[k] && os.IsNotExist(err) {
+			slotPath = path
+		}
+	}
+
+	baseRef := e.deps.BaseRef()
+	if _, err := e.deps.Git.Run("-C", wt.Path, "checkout", "-q", "--detach", baseRef); err != nil {
+		return fmt.Errorf("checkout %s: %w", baseRef, err)
+	}
+	if _, err := e.deps.Git.Run("worktree", "move", wt.Path, slotPath); err != nil {
+		return fmt.Errorf("moving to %s: %w", slotPath, err)
+	}
+	if _, err := e.deps.Git.Run("branch", "-D", wt.Branch); err != nil {
+		e.deps.Logf("warning: could not delete branch %s: %v", wt.Branch, err)
+	}
+	e.deps.Logf("🔥 Returned %s to the pool as %s", wt.Branch, slotPath)
+	return nil
+}
+
+// runAttached runs argv in dir with our stdio. Interrupts from the
+// terminal reach the command through the process group; wt-cycle ignores
+// them meanwhile so that it can still tidy up once the command exits, and
+// passes SIGTERM on.
+func runAttached(dir string, argv []string) error {
+	c := exec.Command(argv[0], argv[1:]...)
+	c.Dir = dir
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(sigs)
+		close(sigs)
+	}()
+	if err := c.Start(); err != nil {
+		return err
+	}
+	go func() {
+		for s := range sigs {
+			if s == syscall.SIGTERM {
+				c.Process.Signal(s)
+			}
+		}
+	}()
+	return c.Wait()
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/lease"
+	"github.com/sestinj/wt-cycle/internal/lock"
+)
+
+// execRepo returns a repo with no worktrees besides main, so exec creates
+// wt-1, which reports ahead commits of its branch over the base ref.
+func execRepo(t *testing.T, ahead string) (*mockGit, string) {
+	t.Helper()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+	wtPath := filepath.Join(tmpDir, "myrepo.wt-1")
+
+	g := &mockGit{
+		currentBranch: "main",
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/main\n\n", repoRoot),
+		cleanPaths:    map[string]bool{wtPath: true},
+		repoRoot:      repoRoot,
+	}
+	g.runFn = func(args []string) (string, error) {
+		switch {
+		case len(args) > 2 && args[2] == "symbolic-ref":
+			return "wt-1\n", nil
+		case args[0] == "rev-list":
+			return ahead + "\n", nil
+		}
+		return "", nil
+	}
+	return g, tmpDir
+}
+
+func TestDoExec_ReturnsUnchangedWorktreeToPool(t *testing.T) {
+	g, tmpDir := execRepo(t, "0")
+	wtPath := filepath.Join(tmpDir, "myrepo.wt-1")
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.deps.Leases = lease.New(g.repoRoot)
+	e.claimOwner = "agent-1"
+	e.poolSize = 1
+	var ranIn string
+	e.runCommand = func(dir string, argv []string) error {
+		ranIn = dir
+		if l, _ := e.deps.Leases.Active("wt-1"); l == nil || l.Owner != "agent-1" {
+			t.Errorf("wt-1 not leased while the command runs: %+v", l)
+		}
+		return exec.Command("sh", "-c", "exit 3").Run()
+	}
+
+	err := e.doExec([]string{"make", "test"}, false, nil)
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("err = %v, want exit status 3", err)
+	}
+	if ranIn != wtPath {
+		t.Errorf("command ran in %q, want %q", ranIn, wtPath)
+	}
+	if l, _ := e.deps.Leases.Get("wt-1"); l != nil {
+		t.Errorf("lease not released: %+v", l)
+	}
+	m := mutations(g)
+	if len(m) != 3 {
+		t.Fatalf("expected 3 mutating git calls, got %v", m)
+	}
+	assertArgs(t, m[0], "-C", wtPath, "checkout", "-q", "--detach", "origin/main")
+	assertArgs(t, m[1], "worktree", "move", wtPath, filepath.Join(tmpDir, "myrepo.pool-1"))
+	assertArgs(t, m[2], "branch", "-D", "wt-1")
+}
+
+// TestDoExec_RemovesUnchangedWorktreeWhenPoolIsFull checks that without
+// room in the pool, here because no pool size is set, an unchanged
+// worktree is removed rather than added as another slot.
+func TestDoExec_RemovesUnchangedWorktreeWhenPoolIsFull(t *testing.T) {
+	g, _ := execRepo(t, "0")
+	e, _ := testEnv(t, g, &mockGH{})
+	var wtCalls [][]string
+	runWt := e.runWt
+	e.runWt = func(args ...string) error {
+		wtCalls = append(wtCalls, args)
+		return runWt(args...)
+	}
+	e.runCommand = func(string, []string) error { return nil }
+
+	if err := e.doExec([]string{"true"}, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(wtCalls) != 2 {
+		t.Fatalf("expected wt switch and remove, got %v", wtCalls)
+	}
+	assertArgs(t, wtCalls[1], "remove", "-y", "wt-1")
+	m := mutations(g)
+	if len(m) != 1 {
+		t.Fatalf("expected 1 mutating git call, got %v", m)
+	}
+	assertArgs(t, m[0], "branch", "-D", "wt-1")
+}
+
+func TestDoExec_KeepsChangedWorktree(t *testing.T) {
+	tests := []struct {
+		name  string
+		ahead string
+		clean bool
+		keep  bool
+	}{
+		{"new commits", "2", true, false},
+		{"uncommitted changes", "0", false, false},
+		{"--keep", "0", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, tmpDir := execRepo(t, tt.ahead)
+			g.cleanPaths[filepath.Join(tmpDir, "myrepo.wt-1")] = tt.clean
+
+			e, _ := testEnv(t, g, &mockGH{})
+			e.runCommand = func(string, []string) error { return nil }
+
+			if err := e.doExec([]string{"true"}, tt.keep, nil); err != nil {
+				t.Fatal(err)
+			}
+			if m := mutations(g); len(m) != 0 {
+				t.Errorf("expected the worktree to be kept, got %v", m)
+			}
+		})
+	}
+}
+
+func TestDoExec_CommandNotFound(t *testing.T) {
+	g, _ := execRepo(t, "0")
+	e, _ := testEnv(t, g, &mockGH{})
+	e.runCommand = func(dir string, argv []string) error {
+		return exec.Command("wt-cycle-no-such-command").Run()
+	}
+
+	err := e.doExec([]string{"wt-cycle-no-such-command"}, false, nil)
+	var exitErr *ExitError
+	if err == nil || errors.As(err, &exitErr) {
+		t.Errorf("err = %v, want a plain error", err)
+	}
+}
+
+// TestDoExec_KeepsWorktreeWithoutLock checks that exec leaves the worktree
+// alone when it cannot take the repo lock back after the command.
+func TestDoExec_KeepsWorktreeWithoutLock(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	old := execRelockTimeout
+	execRelockTimeout = 100 * time.Millisecond
+	defer func() { execRelockTimeout = old }()
+
+	g, _ := execRepo(t, "0")
+	e, _ := testEnv(t, g, &mockGH{})
+	e.deps.Leases = lease.New(g.repoRoot)
+	e.poolSize = 1
+	lk := lock.New(g.repoRoot)
+	if err := lk.Acquire(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	other := lock.New(g.repoRoot)
+	defer other.Release()
+	e.runCommand = func(string, []string) error {
+		// Someone else takes the lock while the command runs.
+		if err := other.Acquire(time.Second); err != nil {
+			t.Error(err)
+		}
+		return nil
+	}
+
+	if err := e.doExec([]string{"true"}, false, lk); err != nil {
+		t.Fatal(err)
+	}
+	if m := mutations(g); len(m) != 0 {
+		t.Errorf("expected the worktree to be kept, got %v", m)
+	}
+	if l, _ := e.deps.Leases.Get("wt-1"); l != nil {
+		t.Errorf("lease not released: %+v", l)
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sestinj/wt-cycle/internal/config"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show what wt-cycle changed in this repo",
+	Long: "Prints the journal of git and worktrunk commands run by wt-cycle, grouped by invocation, " +
+		"most recent last. Each entry shows the branch and commits it touched.",
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+var historyLimit int
+
+func init() {
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "number of invocations to show (0 for all)")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	gitClient := gitpkg.NewExecClient()
+
+	repoRoot, err := gitClient.RepoRoot()
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	e, err := newEnv(gitClient, repoRoot, config.Load(repoRoot))
+	if err != nil {
+		return err
+	}
+	return e.doHistory(historyLimit)
+}
+
+func (e *env) doHistory(limit int) error {
+	ops, err := e.journalOps()
+	if err != nil {
+		return err
+	}
+	if limit > 0 && len(ops) > limit {
+		ops = ops[len(ops)-limit:]
+	}
+
+	if e.jsonOut {
+		shown := []journal.Entry{}
+		for _, op := range ops {
+			shown = append(shown, op.Entries...)
+		}
+		enc := json.NewEncoder(e.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(shown)
+	}
+
+	if len(ops) == 0 {
+		fmt.Fprintln(e.stdout, "No history yet.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	for i, op := range ops {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		title := op.Command
+		if op.Undoes != "" {
+			title += " of " + op.Undoes
+		}
+		fmt.Fprintf(w, "%s  %s  (op %s)\n", op.Time.Local().Format("2006-01-02 15:04:05"), title, op.ID)
+		for _, en := range op.Entries {
+			fmt.Fprintf(w, "  %s %s\t%s\n", en.Tool, strings.Join(en.Args, " "), describeEntry(en))
+		}
+	}
+	return w.Flush()
+}
+
+// journalOps reads the journal grouped by invocation.
+func (e *env) journalOps() ([]journal.Op, error) {
+	if e.journal == nil {
+		return nil, nil
+	}
+	entries, err := e.journal.Entries()
+	if err != nil {
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+	return journal.Ops(entries), nil
+}
+
+// describeEntry summarizes the commits an entry moved between.
+func describeEntry(en journal.Entry) string {
+	var parts []string
+	switch {
+	case en.OldSHA != "" && en.NewSHA != "":
+		parts = append(parts, shortSHA(en.OldSHA)+" -> "+shortSHA(en.NewSHA))
+	case en.OldSHA != "":
+		parts = append(parts, "was "+shortSHA(en.OldSHA))
+	case en.NewSHA != "":
+		parts = append(parts, "now "+shortSHA(en.NewSHA))
+	}
+	if en.Error != "" {
+		msg, _, _ := strings.Cut(strings.TrimSpace(en.Error), "\n")
+		parts = append(parts, "failed: "+msg)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/journal"
+)
+
+func TestDoHistory(t *testing.T) {
+	g := &mockGit{repoRoot: t.TempDir()}
+	e, write := journalEnv(t, g)
+	var stdout strings.Builder
+	e.stdout = &stdout
+
+	write("clean",
+		journal.Entry{Tool: journal.ToolGit, Args: []string{"branch", "-D", "wt-2"}, Branch: "wt-2", OldSHA: "abc1234567"},
+	)
+	write("next",
+		journal.Entry{Tool: journal.ToolWt, Args: []string{"switch", "-c", "wt-3"}, Error: "exit status 1\nmore"},
+	)
+
+	if err := e.doHistory(0); err != nil {
+		t.Fatal(err)
+	}
+	out := stdout.String()
+	for _, want := range []string{"clean", "git branch -D wt-2", "was abc1234", "wt switch -c wt-3", "failed: exit status 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("history missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "clean") > strings.Index(out, "next") {
+		t.Errorf("expected oldest first:\n%s", out)
+	}
+
+	stdout.Reset()
+	if err := e.doHistory(1); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stdout.String(), "wt-2") {
+		t.Errorf("limit 1 should show only the last invocation:\n%s", stdout.String())
+	}
+}
+
+func TestDoHistory_JSON(t *testing.T) {
+	g := &mockGit{repoRoot: t.TempDir()}
+	e, write := journalEnv(t, g)
+	var stdout strings.Builder
+	e.stdout = &stdout
+	e.jsonOut = true
+
+	write("clean", journal.Entry{Tool: journal.ToolGit, Args: []string{"branch", "-D", "wt-2"}, Branch: "wt-2"})
+
+	if err := e.doHistory(0); err != nil {
+		t.Fatal(err)
+	}
+	var entries []journal.Entry
+	if err := json.Unmarshal([]byte(stdout.String()), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Command != "clean" || entries[0].Branch != "wt-2" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/config"
+	"github.com/sestinj/wt-cycle/internal/cycle"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/lease"
+	"github.com/spf13/cobra"
+)
+
+var claimCmd = &cobra.Command{
+	Use:   "claim [branch]",
+	Short: "Lease a worktree so it is not recycled",
+	Long: "Records that a worktree (the current one by default) belongs to --owner. " +
+		"The lease lasts until released, until --ttl passes without a heartbeat, or until --pid exits if given.",
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLeaseCmd(func(e *env, branch string) error {
+		return e.doClaim(branch, leaseOwner, leasePID, claimTTL)
+	}),
+}
+
+var releaseCmd = &cobra.Command{
+	Use:   "release [branch]",
+	Short: "Release a worktree lease",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: runLeaseCmd(func(e *env, branch string) error {
+		return e.doRelease(branch, leaseOwner, leaseForce)
+	}),
+}
+
+var heartbeatCmd = &cobra.Command{
+	Use:   "heartbeat [branch]",
+	Short: "Renew a worktree lease",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: runLeaseCmd(func(e *env, branch string) error {
+		return e.doHeartbeat(branch, leaseOwner, heartbeatTTL)
+	}),
+}
+
+var (
+	leaseOwner   string
+	leasePID     int
+	leaseForce   bool
+	claimTTL     time.Duration
+	heartbeatTTL time.Duration
+)
+
+func init() {
+	for _, c := range []*cobra.Command{claimCmd, releaseCmd, heartbeatCmd} {
+		c.Flags().StringVar(&leaseOwner, "owner", defaultOwner(), "lease owner (default $WT_CYCLE_OWNER or $USER)")
+		rootCmd.AddCommand(c)
+	}
+	claimCmd.Flags().DurationVar(&claimTTL, "ttl", lease.DefaultTTL, "expire the lease if no heartbeat arrives within this duration")
+	claimCmd.Flags().IntVar(&leasePID, "pid", 0, "also release the lease when this process exits (default: TTL only)")
+	heartbeatCmd.Flags().DurationVar(&heartbeatTTL, "ttl", 0, "set a new TTL (default keeps the current one)")
+	releaseCmd.Flags().BoolVar(&leaseForce, "force", false, "release even if held by another owner")
+}
+
+// defaultOwner identifies the lease holder when --owner is not given.
+func defaultOwner() string {
+	if o := os.Getenv("WT_CYCLE_OWNER"); o != "" {
+		return o
+	}
+	if u := os.Getenv("USER"); u != "" {
+		return u
+	}
+	return "unknown"
+}
+
+// runLeaseCmd wraps a lease operation with repo discovery and locking.
+// The branch argument defaults to the current branch.
+func runLeaseCmd(fn func(e *env, branch string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		gitClient := gitpkg.NewExecClient()
+
+		repoRoot, err := gitClient.RepoRoot()
+		if err != nil {
+			return fmt.Errorf("not in a git repository: %w", err)
+		}
+
+		e, err := newEnv(gitClient, repoRoot, config.Load(repoRoot))
+		if err != nil {
+			return err
+		}
+
+		branch := ""
+		if len(args) > 0 {
+			branch = args[0]
+		} else if branch, err = gitClient.CurrentBranch(); err != nil || branch == "" {
+			return fmt.Errorf("no branch given and HEAD is detached")
+		}
+
+		lk, err := acquireLock(gitClient)
+		if err != nil {
+			return err
+		}
+		defer lk.Release()
+
+		return fn(e, branch)
+	}
+}
+
+func (e *env) doClaim(branch, owner string, pid int, ttl time.Duration) error {
+	path, err := cycle.WorktreePath(e.deps, branch)
+	if err != nil {
+		return err
+	}
+	l := lease.Lease{Branch: branch, Path: path, Owner: owner, PID: pid, TTL: ttl}
+	if err := e.deps.Leases.Claim(l); err != nil {
+		return err
+	}
+	e.deps.Logf("🔒 %s leased to %s", branch, l.Describe())
+	return nil
+}
+
+func (e *env) doRelease(branch, owner string, force bool) error {
+	if err := e.deps.Leases.Release(branch, owner, force); err != nil {
+		return err
+	}
+	e.deps.Logf("🔓 %s released", branch)
+	return nil
+}
+
+func (e *env) doHeartbeat(branch, owner string, ttl time.Duration) error {
+	return e.deps.Leases.Heartbeat(branch, owner, ttl)
+}
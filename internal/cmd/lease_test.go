@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/lease"
+)
+
+// testLeases returns a lease store rooted in a temporary state directory.
+func testLeases(t *testing.T) *lease.Store {
+	t.Helper()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	return lease.New(t.TempDir())
+}
+
+func TestDoClaim_SkipsInFindAndShowsInList(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+	}
+
+	e, stdout := testEnv(t, g, &mockGH{})
+	e.deps.Leases = testLeases(t)
+	e.jsonOut = true
+
+	if err := e.doClaim("wt-1", "agent-a", os.Getpid(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.doList(); err != nil {
+		t.Fatal(err)
+	}
+	var statuses []wtStatus
+	if err := json.Unmarshal(stdout.Bytes(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 {
+		t.Fatalf("expected 1 status, got %+v", statuses)
+	}
+	s := statuses[0]
+	if s.Recyclable || s.Reason != "leased" {
+		t.Errorf("expected leased and not recyclable, got %+v", s)
+	}
+	if !strings.HasPrefix(s.Owner, "agent-a") {
+		t.Errorf("owner = %q, want agent-a", s.Owner)
+	}
+
+	// Another owner can't claim it
+	if err := e.doClaim("wt-1", "agent-b", 0, time.Hour); err == nil {
+		t.Error("expected conflict claiming a leased worktree")
+	}
+
+	// After release it becomes recyclable again
+	if err := e.doRelease("wt-1", "agent-a", false); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if err := e.doList(); err != nil {
+		t.Fatal(err)
+	}
+	statuses = nil
+	json.Unmarshal(stdout.Bytes(), &statuses)
+	if len(statuses) != 1 || !statuses[0].Recyclable || statuses[0].Owner != "" {
+		t.Errorf("expected recyclable and unowned after release, got %+v", statuses)
+	}
+}
+
+func TestDoClaim_NoWorktree(t *testing.T) {
+	g := &mockGit{currentBranch: "main", repoRoot: t.TempDir()}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.deps.Leases = testLeases(t)
+
+	if err := e.doClaim("wt-9", "a", 0, time.Hour); err == nil {
+		t.Fatal("expected error claiming a branch without a worktree")
+	}
+}
+
+func TestDoHeartbeat_RequiresOwner(t *testing.T) {
+	dir := t.TempDir()
+	g := &mockGit{
+		currentBranch: "main",
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\n", dir),
+		repoRoot:      dir,
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.deps.Leases = testLeases(t)
+
+	if err := e.doHeartbeat("wt-1", "a", 0); err == nil {
+		t.Error("expected error heartbeating an unleased worktree")
+	}
+	e.doClaim("wt-1", "a", 0, time.Minute)
+	if err := e.doHeartbeat("wt-1", "b", 0); err == nil {
+		t.Error("expected error heartbeating someone else's lease")
+	}
+	if err := e.doHeartbeat("wt-1", "a", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	l, _ := e.deps.Leases.Active("wt-1")
+	if l == nil || l.TTL != time.Hour {
+		t.Errorf("expected TTL updated to 1h, got %+v", l)
+	}
+}
+
+func TestDoNext_Claim(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+		refs:          []string{"wt-1"},
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.deps.Leases = testLeases(t)
+	e.claimOwner = "agent-a"
+	e.claimTTL = time.Hour
+
+	if err := e.doNext(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Recycled wt-1 into wt-2, which is now leased
+	l, err := e.deps.Leases.Active("wt-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l == nil || l.Owner != "agent-a" || l.Path != dir {
+		t.Errorf("lease = %+v, want agent-a on %s", l, dir)
+	}
+	// Without --pid the lease is bound to its TTL only.
+	if l != nil && l.PID != 0 {
+		t.Errorf("lease PID = %d, want 0", l.PID)
+	}
+}
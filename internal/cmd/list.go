@@ -3,8 +3,10 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"text/tabwriter"
 
+	"github.com/sestinj/wt-cycle/internal/config"
 	"github.com/sestinj/wt-cycle/internal/cycle"
 	gitpkg "github.com/sestinj/wt-cycle/internal/git"
 	"github.com/spf13/cobra"
@@ -26,6 +28,9 @@ type wtStatus struct {
 	Path       string `json:"path"`
 	Recyclable bool   `json:"recyclable"`
 	Reason     string `json:"reason,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+	Owner      string `json:"owner,omitempty"`
+	PRState    string `json:"pr_state,omitempty"` // MERGED or CLOSED
 	Current    bool   `json:"current"`
 }
 
@@ -37,7 +42,10 @@ func runList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("not in a git repository: %w", err)
 	}
 
-	e := newEnv(gitClient, repoRoot)
+	e, err := newEnv(gitClient, repoRoot, config.Load(repoRoot))
+	if err != nil {
+		return err
+	}
 	return e.doList()
 }
 
@@ -57,20 +65,35 @@ func (e *env) doList() error {
 
 	// Build lookup maps from FindResult
 	recyclableSet := make(map[string]bool)
+	prStates := make(map[string]string)
 	for _, r := range result.Recyclable {
 		recyclableSet[r.Branch] = true
+		prStates[r.Branch] = r.PRState
 	}
-	skippedReason := make(map[string]string)
+	skippedByBranch := make(map[string]cycle.Skipped)
 	for _, s := range result.Skipped {
-		skippedReason[s.Branch] = s.Reason
+		skippedByBranch[s.Branch] = s
+		prStates[s.Branch] = s.PRState
+	}
+
+	owners := make(map[string]string)
+	if e.deps.Leases != nil {
+		leases, err := e.deps.Leases.List()
+		if err != nil {
+			e.deps.Logf("warning: reading leases: %v", err)
+		}
+		for _, l := range leases {
+			owners[l.Branch] = l.Describe()
+		}
 	}
 
 	currentBranch, _ := e.deps.Git.CurrentBranch()
 
-	// Build status list for wt-N worktrees
+	// Build status list for numbered worktrees
+	names := e.deps.Names()
 	var statuses []wtStatus
 	for _, wt := range allWts {
-		if gitpkg.ExtractWtNum(wt.Branch) < 0 {
+		if names.Num(wt.Branch) < 0 {
 			continue
 		}
 		s := wtStatus{
@@ -78,9 +101,12 @@ func (e *env) doList() error {
 			Path:       wt.Path,
 			Current:    wt.Branch == currentBranch,
 			Recyclable: recyclableSet[wt.Branch],
+			Owner:      owners[wt.Branch],
+			PRState:    prStates[wt.Branch],
 		}
-		if reason, ok := skippedReason[wt.Branch]; ok {
-			s.Reason = reason
+		if skip, ok := skippedByBranch[wt.Branch]; ok {
+			s.Reason = skip.Reason
+			s.Detail = skip.Detail
 		} else if !s.Recyclable {
 			s.Reason = "active" // not a candidate (not merged/closed)
 		}
@@ -99,7 +125,7 @@ func (e *env) doList() error {
 	}
 
 	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
-	fmt.Fprintln(w, "BRANCH\tPATH\tSTATUS\tREASON")
+	fmt.Fprintln(w, "BRANCH\tPATH\tSTATUS\tPR\tOWNER\tREASON")
 	for _, s := range statuses {
 		status := "active"
 		if s.Current {
@@ -110,8 +136,18 @@ func (e *env) doList() error {
 		reason := s.Reason
 		if reason == "" {
 			reason = "-"
+		} else if s.Detail != "" {
+			reason += " (" + s.Detail + ")"
+		}
+		owner := s.Owner
+		if owner == "" {
+			owner = "-"
+		}
+		pr := strings.ToLower(s.PRState)
+		if pr == "" {
+			pr = "-"
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Branch, s.Path, status, reason)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Branch, s.Path, status, pr, owner, reason)
 	}
 	w.Flush()
 
@@ -3,8 +3,13 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/gitea"
+	"github.com/sestinj/wt-cycle/internal/procs"
 )
 
 func TestDoList_Table_HappyPath(t *testing.T) {
@@ -269,3 +274,59 @@ func TestDoList_SkippedReasons(t *testing.T) {
 		t.Error("expected recyclable=false for dirty worktree")
 	}
 }
+
+func TestDoList_InUse(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+	}
+
+	e, stdout := testEnv(t, g, &mockGH{})
+	e.deps.Processes = func() ([]procs.Process, error) {
+		return []procs.Process{{PID: 99, Command: "vim", Cwd: dir}}, nil
+	}
+
+	if err := e.doList(); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := stdout.String(); !strings.Contains(out, "in-use (99 vim)") {
+		t.Errorf("expected 'in-use (99 vim)' in output, got:\n%s", out)
+	}
+}
+
+func TestDoList_GiteaClosedPR(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"head": {"ref": "wt-2", "sha": "abc"}, "merged": false}]`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	g := &mockGit{
+		currentBranch: "main",
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-2\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      "/repo",
+		refs:          []string{"wt-2"},
+	}
+
+	e, stdout := testEnv(t, g, nil)
+	e.deps.Forge = &gitea.Client{APIURL: srv.URL + "/api/v1", Owner: "acme", Repo: "widgets"}
+	e.jsonOut = true
+
+	if err := e.doList(); err != nil {
+		t.Fatal(err)
+	}
+	var statuses []wtStatus
+	if err := json.Unmarshal(stdout.Bytes(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || !statuses[0].Recyclable {
+		t.Errorf("expected wt-2 recyclable from a closed Gitea PR, got %+v", statuses)
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/cycle"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/lock"
+	"github.com/spf13/cobra"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Inspect the repo lock",
+}
+
+var lockStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show who holds the repo lock",
+	Long: "Shows whether the lock that serializes wt-cycle commands on this repository is held, the holder's " +
+		"PID, command and start time, and how many commands are queued behind it. The lock is released " +
+		"when its holder exits, so it never needs to be broken by hand.",
+	Args: cobra.NoArgs,
+	RunE: runLockStatus,
+}
+
+func init() {
+	lockCmd.AddCommand(lockStatusCmd)
+	rootCmd.AddCommand(lockCmd)
+}
+
+func runLockStatus(cmd *cobra.Command, args []string) error {
+	gitClient := gitpkg.NewExecClient()
+	if _, err := gitClient.RepoRoot(); err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+	lk, err := repoLock(gitClient)
+	if err != nil {
+		return err
+	}
+	s, err := lk.Status()
+	if err != nil {
+		return fmt.Errorf("reading lock: %w", err)
+	}
+	return writeLockStatus(os.Stdout, s, jsonOut, time.Now())
+}
+
+// repoLock returns the lock for gitClient's repository. Like leases and the
+// journal, it is keyed by the main worktree, so that commands run from
+// different worktrees of one repo wait for each other.
+func repoLock(gitClient gitpkg.Client) (*lock.Lock, error) {
+	mainRoot, err := cycle.MainWorktree(&cycle.Deps{Git: gitClient})
+	if err != nil {
+		return nil, fmt.Errorf("locating main worktree: %w", err)
+	}
+	return lock.New(mainRoot), nil
+}
+
+// acquireLock waits for the repo lock; the caller releases it.
+func acquireLock(gitClient gitpkg.Client) (*lock.Lock, error) {
+	lk, err := repoLock(gitClient)
+	if err != nil {
+		return nil, err
+	}
+	if err := lk.Acquire(lock.DefaultTimeout); err != nil {
+		return nil, fmt.Errorf("acquiring lock: %w", err)
+	}
+	return lk, nil
+}
+
+func writeLockStatus(w io.Writer, s lock.Status, jsonOut bool, now time.Time) error {
+	if jsonOut {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+
+	switch {
+	case !s.Held:
+		fmt.Fprintln(w, "free")
+	case s.Holder == nil:
+		fmt.Fprintln(w, "held (holder not recorded yet)")
+	default:
+		fmt.Fprintf(w, "held by %s (%s ago)\n", s.Holder.Describe(), now.Sub(s.Holder.Started).Round(time.Second))
+	}
+	if s.Waiting > 0 {
+		fmt.Fprintf(w, "%d waiting\n", s.Waiting)
+	}
+	fmt.Fprintf(w, "lock file: %s\n", s.Path)
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/lock"
+)
+
+func TestWriteLockStatus(t *testing.T) {
+	started := time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local)
+	held := lock.Status{
+		Path:    "/run/user/1000/wt-cycle/abc.lock",
+		Held:    true,
+		Holder:  &lock.Holder{PID: 4242, Command: "wt-cycle next --claim", Started: started},
+		Waiting: 2,
+	}
+
+	var out strings.Builder
+	writeLockStatus(&out, held, false, started.Add(90*time.Second))
+	want := "held by PID 4242 (wt-cycle next --claim) since 09:30:00 (1m30s ago)\n" +
+		"2 waiting\nlock file: /run/user/1000/wt-cycle/abc.lock\n"
+	if out.String() != want {
+		t.Errorf("held =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	writeLockStatus(&out, lock.Status{Path: held.Path}, false, started)
+	if want := "free\nlock file: " + held.Path + "\n"; out.String() != want {
+		t.Errorf("free = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	writeLockStatus(&out, held, true, started)
+	var got lock.Status
+	if err := json.Unmarshal([]byte(out.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Held || got.Holder == nil || got.Holder.PID != 4242 || got.Waiting != 2 {
+		t.Errorf("json = %s", out.String())
+	}
+}
+
+// TestLockStatus_Live reports a lock held through the lock package.
+func TestLockStatus_Live(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	lk := lock.New(t.TempDir())
+	if err := lk.Acquire(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	defer lk.Release()
+
+	s, err := lk.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	writeLockStatus(&out, s, false, time.Now())
+	if want := fmt.Sprintf("held by PID %d (", os.Getpid()); !strings.HasPrefix(out.String(), want) {
+		t.Errorf("status = %q, want prefix %q", out.String(), want)
+	}
+}
+
+// TestRepoLock_SharedAcrossWorktrees checks that a linked worktree gets
+// the same lock as the main worktree.
+func TestRepoLock_SharedAcrossWorktrees(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	g := &mockGit{
+		wtPorcelain: "worktree /repo\nHEAD abc\nbranch refs/heads/main\n\n" +
+			"worktree /repo.wt-1\nHEAD abc\nbranch refs/heads/wt-1\n\n",
+		repoRoot: "/repo.wt-1",
+	}
+	lk, err := repoLock(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := lock.New("/repo").Path(); lk.Path() != want {
+		t.Errorf("lock from wt-1 = %s, want the main worktree's %s", lk.Path(), want)
+	}
+}
@@ -2,13 +2,14 @@ package cmd
 
 import (
 	"fmt"
-	"path/filepath"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/sestinj/wt-cycle/internal/config"
 	"github.com/sestinj/wt-cycle/internal/cycle"
 	gitpkg "github.com/sestinj/wt-cycle/internal/git"
-	"github.com/sestinj/wt-cycle/internal/lock"
+	"github.com/sestinj/wt-cycle/internal/hooks"
+	"github.com/sestinj/wt-cycle/internal/lease"
 	"github.com/spf13/cobra"
 )
 
@@ -19,7 +20,18 @@ var nextCmd = &cobra.Command{
 	RunE:  runNext,
 }
 
+var (
+	nextClaim bool
+	nextTTL   time.Duration
+)
+
 func init() {
+	nextCmd.Flags().BoolVar(&nextClaim, "claim", false, "lease the worktree to --owner")
+	nextCmd.Flags().StringVar(&leaseOwner, "owner", defaultOwner(), "lease owner for --claim")
+	nextCmd.Flags().DurationVar(&nextTTL, "ttl", lease.DefaultTTL, "lease TTL for --claim")
+	nextCmd.Flags().IntVar(&leasePID, "pid", 0, "with --claim, also release the lease when this process exits (default: TTL only)")
+	nextCmd.Flags().BoolVar(&archiveStashes, "archive-stashes", false, "archive stashes on recyclable branches under refs/wt-cycle/stash/ instead of skipping them")
+	nextCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "print the git and wt commands next would run, without running them")
 	rootCmd.AddCommand(nextCmd)
 }
 
@@ -32,95 +44,216 @@ func runNext(cmd *cobra.Command, args []string) error {
 	}
 
 	// If this repo is in the skip list, just print the repo root and exit
-	if cfg := config.Load(); cfg.ShouldSkip(repoRoot) {
+	cfg := config.Load(repoRoot)
+	if cfg.ShouldSkip(repoRoot) {
 		fmt.Println(repoRoot)
 		return nil
 	}
 
 	// Acquire lock
-	lk := lock.New(repoRoot)
-	if err := lk.Acquire(lock.DefaultTimeout); err != nil {
-		return fmt.Errorf("acquiring lock: %w", err)
+	lk, err := acquireLock(gitClient)
+	if err != nil {
+		return err
 	}
 	defer lk.Release()
 
-	e := newEnv(gitClient, repoRoot)
+	e, err := newEnv(gitClient, repoRoot, cfg)
+	if err != nil {
+		return err
+	}
+	if nextClaim {
+		e.claimOwner = leaseOwner
+		e.claimTTL = nextTTL
+		e.claimPID = leasePID
+	}
+	e.archiveStashes = archiveStashes
+	if dryRun {
+		p := e.startDryRun()
+		if err := e.doNext(); err != nil {
+			return err
+		}
+		return p.write(e.stdout, e.jsonOut)
+	}
 	return e.doNext()
 }
 
 func (e *env) doNext() error {
-	// Find recyclable worktrees
-	result, err := cycle.FindRecyclable(e.deps)
+	wt, err := e.acquireWorktree()
 	if err != nil {
 		return err
 	}
+	if e.plan != nil {
+		return nil
+	}
+
+	if e.claimOwner != "" {
+		err := e.deps.Leases.Claim(lease.Lease{
+			Branch: wt.Branch,
+			Path:   wt.Path,
+			Owner:  e.claimOwner,
+			PID:    e.claimPID,
+			TTL:    e.claimTTL,
+		})
+		if err != nil {
+			return fmt.Errorf("claiming %s: %w", wt.Branch, err)
+		}
+	}
 
-	// Compute next wt-N number
+	// Print the worktree path
+	fmt.Fprintln(e.stdout, wt.Path)
+	return nil
+}
+
+// acquireWorktree recycles the first recyclable worktree or creates a new
+// one, leaving the process chdir'd into it.
+func (e *env) acquireWorktree() (gitpkg.Worktree, error) {
+	// Find recyclable worktrees
+	result, err := e.findRecyclable()
+	if err != nil {
+		return gitpkg.Worktree{}, err
+	}
+
+	// Compute next branch number
 	existingNums, err := cycle.CollectExistingNums(e.deps)
 	if err != nil {
-		return fmt.Errorf("collecting existing numbers: %w", err)
+		return gitpkg.Worktree{}, fmt.Errorf("collecting existing numbers: %w", err)
 	}
 	nextNum := cycle.NextNum(existingNums)
-	newBranch := fmt.Sprintf("wt-%d", nextNum)
+	newBranch := e.deps.Names().Branch(nextNum)
 
 	if len(result.Recyclable) > 0 {
 		return e.recycleWorktree(result.Recyclable[0], newBranch)
 	}
+
+	// A warm pool slot only needs a branch, which is much faster than
+	// creating a worktree from scratch.
+	slots, err := cycle.PoolSlots(e.deps)
+	if err != nil {
+		e.deps.Logf("warning: listing pool slots: %v", err)
+	} else if slot, ok := cycle.FreePoolSlot(e.deps, slots); ok {
+		wt, err := e.takePoolSlot(slot, newBranch)
+		if err != nil {
+			return gitpkg.Worktree{}, err
+		}
+		e.refillPool(len(slots))
+		return wt, nil
+	}
+
 	return e.createWorktree(newBranch)
 }
 
-func (e *env) recycleWorktree(target cycle.Recyclable, newBranch string) error {
+func (e *env) recycleWorktree(target cycle.Recyclable, newBranch string) (gitpkg.Worktree, error) {
 	e.deps.Logf("♻️  Recycling %s", target.Branch)
 
 	// Switch to the recyclable worktree
-	if err := e.runWt("switch", target.Branch); err != nil {
-		return fmt.Errorf("wt switch %s: %w", target.Branch, err)
+	if err := e.worktrees().Switch(target.Branch); err != nil {
+		return gitpkg.Worktree{}, fmt.Errorf("switching to %s: %w", target.Branch, err)
 	}
 
-	// wt switch runs as a subprocess and cannot change the parent
+	// The backend runs as a subprocess and cannot change the parent
 	// process's cwd. Explicitly chdir so subsequent git commands
 	// target the correct worktree.
 	if err := e.chdir(target.Path); err != nil {
-		return fmt.Errorf("chdir to %s: %w", target.Path, err)
+		return gitpkg.Worktree{}, fmt.Errorf("chdir to %s: %w", target.Path, err)
 	}
 
 	// Detach HEAD, delete old branch, create new
-	e.deps.Logf("🔄 Updating to latest main and creating branch %s", newBranch)
-	if _, err := e.deps.Git.Run("checkout", "-q", "origin/main"); err != nil {
-		return fmt.Errorf("checkout origin/main: %w", err)
+	baseRef := e.deps.BaseRef()
+	e.deps.Logf("🔄 Updating to latest %s and creating branch %s", e.deps.BranchName(), newBranch)
+	if _, err := e.deps.Git.Run("checkout", "-q", baseRef); err != nil {
+		return gitpkg.Worktree{}, fmt.Errorf("checkout %s: %w", baseRef, err)
 	}
-	if _, err := e.deps.Git.Run("branch", "-D", target.Branch); err != nil {
-		e.deps.Logf("warning: could not delete branch %s: %v", target.Branch, err)
+	if e.archiveBranch(target) {
+		if _, err := e.deps.Git.Run("branch", "-D", target.Branch); err != nil {
+			e.deps.Logf("warning: could not delete branch %s: %v", target.Branch, err)
+		}
 	}
 	if _, err := e.deps.Git.Run("checkout", "-q", "-b", newBranch); err != nil {
-		return fmt.Errorf("checkout -b %s: %w", newBranch, err)
+		return gitpkg.Worktree{}, fmt.Errorf("checkout -b %s: %w", newBranch, err)
 	}
 
-	// Print the worktree path
-	fmt.Fprintln(e.stdout, target.Path)
-	return nil
+	if err := e.hook(hooks.PostRecycle, newBranch, target.Path); err != nil {
+		e.deps.Logf("warning: %v", err)
+	}
+	return gitpkg.Worktree{Path: target.Path, Branch: newBranch}, nil
+}
+
+// takePoolSlot moves a warm slot to newBranch's path and creates the
+// branch there on top of the latest base ref.
+func (e *env) takePoolSlot(slot cycle.PoolSlot, newBranch string) (gitpkg.Worktree, error) {
+	e.deps.Logf("⚡ Using warm pool slot %d for %s", slot.Num, newBranch)
+
+	mainRoot, err := cycle.MainWorktree(e.deps)
+	if err != nil {
+		return gitpkg.Worktree{}, fmt.Errorf("locating main worktree: %w", err)
+	}
+	newPath := e.deps.Layout().Path(mainRoot, newBranch)
+	if _, err := e.deps.Git.Run("worktree", "move", slot.Path, newPath); err != nil {
+		return gitpkg.Worktree{}, fmt.Errorf("moving pool slot %s: %w", slot.Path, err)
+	}
+	if err := e.chdir(newPath); err != nil {
+		return gitpkg.Worktree{}, fmt.Errorf("chdir to %s: %w", newPath, err)
+	}
+	baseRef := e.deps.BaseRef()
+	if _, err := e.deps.Git.Run("checkout", "-q", "-b", newBranch, baseRef); err != nil {
+		return gitpkg.Worktree{}, fmt.Errorf("checkout -b %s: %w", newBranch, err)
+	}
+
+	// The slot already ran post-create when it was warmed; from here on it
+	// is a reused worktree like any other.
+	if err := e.hook(hooks.PostRecycle, newBranch, newPath); err != nil {
+		e.deps.Logf("warning: %v", err)
+	}
+	return gitpkg.Worktree{Path: newPath, Branch: newBranch}, nil
 }
 
-func (e *env) createWorktree(newBranch string) error {
+// refillPool tops the pool back up in a background process. Without a
+// configured size, the pool is kept at the size it had before.
+func (e *env) refillPool(hadSlots int) {
+	if e.spawn == nil {
+		return
+	}
+	size := e.poolSize
+	if size <= 0 {
+		size = hadSlots
+	}
+	if err := e.spawn("pool", "warm", "--size", strconv.Itoa(size)); err != nil {
+		e.deps.Logf("warning: could not start pool refill: %v", err)
+	}
+}
+
+func (e *env) createWorktree(newBranch string) (gitpkg.Worktree, error) {
 	e.deps.Logf("✨ Creating %s", newBranch)
 
-	// Create new worktree via worktrunk
-	if err := e.runWt("switch", "-c", newBranch, "--base", "origin/main"); err != nil {
-		return fmt.Errorf("wt switch -c %s: %w", newBranch, err)
+	mainRoot, err := cycle.MainWorktree(e.deps)
+	if err != nil {
+		return gitpkg.Worktree{}, fmt.Errorf("locating main worktree: %w", err)
+	}
+	plannedPath := e.deps.Layout().Path(mainRoot, newBranch)
+
+	if err := e.worktrees().Create(newBranch, e.deps.BaseRef(), plannedPath); err != nil {
+		return gitpkg.Worktree{}, fmt.Errorf("creating worktree %s: %w", newBranch, err)
 	}
 
-	// wt switch runs as a subprocess and cannot change the parent
-	// process's cwd. Compute the worktree path using the same
-	// convention as worktrunk: <parent>/<base-repo-name>.<branch>
-	repoParent := filepath.Dir(e.repoRoot)
-	baseName := filepath.Base(e.repoRoot)
-	if idx := strings.Index(baseName, ".wt-"); idx != -1 {
-		baseName = baseName[:idx]
+	// The backend may place the worktree wherever it is configured to,
+	// so ask git where it actually ended up rather than guessing.
+	newPath, err := cycle.WorktreePath(e.deps, newBranch)
+	if err != nil && e.plan != nil {
+		// Nothing was created in a dry run; assume the planned path.
+		newPath, err = plannedPath, nil
 	}
-	newPath := filepath.Join(repoParent, baseName+"."+newBranch)
+	if err != nil {
+		return gitpkg.Worktree{}, fmt.Errorf("locating new worktree %s: %w", newBranch, err)
+	}
+
+	// The backend runs as a subprocess and cannot change the parent
+	// process's cwd, so chdir explicitly.
 	if err := e.chdir(newPath); err != nil {
-		return fmt.Errorf("chdir to new worktree %s: %w", newPath, err)
+		return gitpkg.Worktree{}, fmt.Errorf("chdir to new worktree %s: %w", newPath, err)
 	}
-	fmt.Fprintln(e.stdout, newPath)
-	return nil
+
+	if err := e.hook(hooks.PostCreate, newBranch, newPath); err != nil {
+		e.deps.Logf("warning: %v", err)
+	}
+	return gitpkg.Worktree{Path: newPath, Branch: newBranch}, nil
 }
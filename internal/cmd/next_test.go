@@ -6,6 +6,10 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/hooks"
+	"github.com/sestinj/wt-cycle/internal/worktree"
 )
 
 // --- Recycling path ---
@@ -344,9 +348,13 @@ func TestDoNext_Create_HappyPath(t *testing.T) {
 		return nil
 	}
 
+	// NextNum([]) = 1, new branch = wt-1
+	expectedPath := filepath.Join(tmpDir, "myrepo.wt-1")
+
 	var wtArgs []string
 	e.runWt = func(args ...string) error {
 		wtArgs = args
+		g.registerWorktree(expectedPath, args[2])
 		return nil
 	}
 
@@ -354,8 +362,6 @@ func TestDoNext_Create_HappyPath(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// NextNum([]) = 1, new branch = wt-1
-	expectedPath := filepath.Join(tmpDir, "myrepo.wt-1")
 	if chdirPath != expectedPath {
 		t.Errorf("chdir = %q, want %q", chdirPath, expectedPath)
 	}
@@ -484,6 +490,7 @@ func TestDoNext_NextNum_GapFilling(t *testing.T) {
 	var wtArgs []string
 	e.runWt = func(args ...string) error {
 		wtArgs = args
+		g.registerWorktree(worktrunkPath(g.repoRoot, args[2]), args[2])
 		return nil
 	}
 
@@ -501,3 +508,257 @@ func TestDoNext_NextNum_GapFilling(t *testing.T) {
 		t.Errorf("stdout = %q, expected path ending in .wt-2", out)
 	}
 }
+
+func TestDoNext_CustomBase(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+		refs:          []string{"wt-1"},
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.deps.Remote = "upstream"
+	e.deps.BaseBranch = "develop"
+
+	if err := e.doNext(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.runCalls) == 0 {
+		t.Fatal("expected git Run calls")
+	}
+	assertArgs(t, g.runCalls[0], "checkout", "-q", "upstream/develop")
+}
+
+func TestDoNext_Create_CustomBase(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+	os.MkdirAll(repoRoot, 0755)
+
+	g := &mockGit{
+		currentBranch: "master",
+		merged:        []string{},
+		cleanPaths:    map[string]bool{},
+		repoRoot:      repoRoot,
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.deps.BaseBranch = "master"
+
+	var wtArgs []string
+	e.runWt = func(args ...string) error {
+		wtArgs = args
+		g.registerWorktree(worktrunkPath(g.repoRoot, args[2]), args[2])
+		return nil
+	}
+
+	if err := e.doNext(); err != nil {
+		t.Fatal(err)
+	}
+	assertArgs(t, wtArgs, "switch", "-c", "wt-1", "--base", "origin/master")
+}
+
+func TestDoNext_Create_CustomNaming(t *testing.T) {
+	t.Setenv("USER", "nate")
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo.agent-nate-1")
+	os.MkdirAll(repoRoot, 0755)
+
+	g := &mockGit{
+		currentBranch: "agent/nate/1",
+		merged:        []string{},
+		cleanPaths:    map[string]bool{},
+		repoRoot:      repoRoot,
+		refs:          []string{"agent/nate/1"},
+	}
+
+	e, stdout := testEnv(t, g, &mockGH{})
+	e.deps.Naming = gitpkg.MustParseNaming("agent/{user}/{n}")
+	expectedPath := filepath.Join(tmpDir, "myrepo.agent-nate-2")
+
+	var wtArgs []string
+	e.runWt = func(args ...string) error {
+		wtArgs = args
+		g.registerWorktree(expectedPath, args[2])
+		return nil
+	}
+
+	if err := e.doNext(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertArgs(t, wtArgs, "switch", "-c", "agent/nate/2", "--base", "origin/main")
+	if got := strings.TrimSpace(stdout.String()); got != expectedPath {
+		t.Errorf("stdout = %q, want %q", got, expectedPath)
+	}
+}
+
+func TestDoNext_Create_NativeBackend(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+	os.MkdirAll(repoRoot, 0755)
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{},
+		cleanPaths:    map[string]bool{},
+		repoRoot:      repoRoot,
+	}
+
+	g.runFn = func(args []string) (string, error) {
+		if args[0] == "worktree" && args[1] == "add" {
+			g.registerWorktree(args[5], args[4])
+		}
+		return "", nil
+	}
+
+	e, stdout := testEnv(t, g, &mockGH{})
+	e.backend = &worktree.Native{Git: g}
+	e.runWt = func(args ...string) error {
+		t.Fatalf("wt should not be called with the native backend, got %v", args)
+		return nil
+	}
+
+	if err := e.doNext(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedPath := filepath.Join(tmpDir, "myrepo.wt-1")
+	if len(g.runCalls) != 1 {
+		t.Fatalf("expected 1 git Run call, got %d: %v", len(g.runCalls), g.runCalls)
+	}
+	assertArgs(t, g.runCalls[0], "worktree", "add", "-q", "-b", "wt-1", expectedPath, "origin/main")
+	if got := strings.TrimSpace(stdout.String()); got != expectedPath {
+		t.Errorf("stdout = %q, want %q", got, expectedPath)
+	}
+}
+
+func TestDoNext_Create_PathReadBackFromGit(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+	os.MkdirAll(repoRoot, 0755)
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{},
+		cleanPaths:    map[string]bool{},
+		repoRoot:      repoRoot,
+	}
+
+	e, stdout := testEnv(t, g, &mockGH{})
+
+	// worktrunk is configured with a different layout than ours
+	actualPath := filepath.Join(tmpDir, "elsewhere", "wt-1")
+	e.runWt = func(args ...string) error {
+		g.registerWorktree(actualPath, args[2])
+		return nil
+	}
+
+	var chdirPath string
+	e.chdir = func(path string) error {
+		chdirPath = path
+		return nil
+	}
+
+	if err := e.doNext(); err != nil {
+		t.Fatal(err)
+	}
+
+	if chdirPath != actualPath {
+		t.Errorf("chdir = %q, want %q", chdirPath, actualPath)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != actualPath {
+		t.Errorf("stdout = %q, want %q", got, actualPath)
+	}
+}
+
+func TestDoNext_Create_WorktreeNotFound(t *testing.T) {
+	repoRoot := filepath.Join(t.TempDir(), "myrepo")
+	os.MkdirAll(repoRoot, 0755)
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{},
+		cleanPaths:    map[string]bool{},
+		repoRoot:      repoRoot,
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.runWt = func(args ...string) error { return nil } // creates nothing
+
+	err := e.doNext()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "locating new worktree") {
+		t.Errorf("error = %q, want it to mention 'locating new worktree'", err)
+	}
+}
+
+func TestDoNext_Create_RunsPostCreateHook(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+	os.MkdirAll(repoRoot, 0755)
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{},
+		cleanPaths:    map[string]bool{},
+		repoRoot:      repoRoot,
+	}
+
+	e, stdout := testEnv(t, g, &mockGH{})
+	var got []hooks.Context
+	e.runHook = func(hc hooks.Context) error {
+		got = append(got, hc)
+		return fmt.Errorf("post-create hook failed: exit status 1")
+	}
+
+	// A failing post-create hook is only a warning.
+	if err := e.doNext(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedPath := filepath.Join(tmpDir, "myrepo.wt-1")
+	want := hooks.Context{Action: hooks.PostCreate, Branch: "wt-1", Path: expectedPath, Num: 1}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("hooks = %+v, want [%+v]", got, want)
+	}
+	if strings.TrimSpace(stdout.String()) != expectedPath {
+		t.Errorf("stdout = %q, want %q", stdout.String(), expectedPath)
+	}
+}
+
+func TestDoNext_Recycle_RunsPostRecycleHook(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+		refs:          []string{"wt-1"},
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	var got []hooks.Context
+	e.runHook = func(hc hooks.Context) error {
+		got = append(got, hc)
+		return nil
+	}
+
+	if err := e.doNext(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := hooks.Context{Action: hooks.PostRecycle, Branch: "wt-2", Path: dir, Num: 2}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("hooks = %+v, want [%+v]", got, want)
+	}
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/sestinj/wt-cycle/internal/cycle"
+	"github.com/sestinj/wt-cycle/internal/forge"
+)
+
+// errCancelled is returned by a chooser when the user backs out.
+var errCancelled = errors.New("cancelled")
+
+// chooseTerminal asks which of labels to select: with a checklist when in
+// is a terminal that can be put in raw mode, otherwise with a y/N prompt
+// per label. Prompts go to out.
+func chooseTerminal(in *os.File, out io.Writer, labels []string) ([]bool, error) {
+	if fi, err := in.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		if restore, err := makeRaw(in); err == nil {
+			defer restore()
+			return runPicker(in, out, labels)
+		}
+	}
+	return promptEach(in, out, labels)
+}
+
+// makeRaw switches the terminal on f to raw mode with stty and returns a
+// function that restores the previous settings.
+func makeRaw(f *os.File) (func(), error) {
+	saved, err := stty(f, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty(f, "raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(f, strings.TrimSpace(saved)) }, nil
+}
+
+func stty(f *os.File, args ...string) (string, error) {
+	c := exec.Command("stty", args...)
+	c.Stdin = f
+	out, err := c.Output()
+	return string(out), err
+}
+
+// runPicker shows labels as a checklist, all checked, and reads keys from
+// in until the user confirms with enter or cancels with q or ctrl-c. in
+// must be in raw mode.
+func runPicker(in io.Reader, out io.Writer, labels []string) ([]bool, error) {
+	selected := make([]bool, len(labels))
+	for i := range selected {
+		selected[i] = true
+	}
+	cursor := 0
+
+	fmt.Fprint(out, "\x1b[?25l") // hide the cursor while drawing
+	defer fmt.Fprint(out, "\x1b[?25h")
+	draw := func(redraw bool) {
+		if redraw {
+			fmt.Fprintf(out, "\x1b[%dA\r\x1b[J", len(labels)+1)
+		}
+		fmt.Fprint(out, "Select worktrees to remove (↑/↓ move, space toggle, a all, enter confirm, q cancel)\r\n")
+		for i, l := range labels {
+			pointer, box := " ", "[ ]"
+			if i == cursor {
+				pointer = ">"
+			}
+			if selected[i] {
+				box = "[x]"
+			}
+			fmt.Fprintf(out, "%s %s %s\r\n", pointer, box, l)
+		}
+	}
+	draw(false)
+
+	r := bufio.NewReader(in)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, errCancelled
+		}
+		switch b {
+		case 'k', 0x10: // ctrl-p
+			cursor = (cursor + len(labels) - 1) % len(labels)
+		case 'j', 0x0e: // ctrl-n
+			cursor = (cursor + 1) % len(labels)
+		case 0x1b: // arrow keys arrive as ESC [ A and ESC [ B
+			if next, _ := r.ReadByte(); next != '[' {
+				continue
+			}
+			switch key, _ := r.ReadByte(); key {
+			case 'A':
+				cursor = (cursor + len(labels) - 1) % len(labels)
+			case 'B':
+				cursor = (cursor + 1) % len(labels)
+			}
+		case ' ':
+			selected[cursor] = !selected[cursor]
+		case 'a':
+			all := true
+			for _, s := range selected {
+				all = all && s
+			}
+			for i := range selected {
+				selected[i] = !all
+			}
+		case '\r', '\n':
+			return selected, nil
+		case 'q', 0x03: // ctrl-c
+			return nil, errCancelled
+		default:
+			continue
+		}
+		draw(true)
+	}
+}
+
+// promptEach asks y/N for each label in turn, reading answers a line at a
+// time from in. Running out of input declines the remaining labels.
+func promptEach(in io.Reader, out io.Writer, labels []string) ([]bool, error) {
+	selected := make([]bool, len(labels))
+	sc := bufio.NewScanner(in)
+	for i, l := range labels {
+		fmt.Fprintf(out, "Remove %s? [y/N] ", l)
+		if !sc.Scan() {
+			fmt.Fprintln(out)
+			break
+		}
+		answer := strings.ToLower(strings.TrimSpace(sc.Text()))
+		selected[i] = answer == "y" || answer == "yes"
+	}
+	return selected, sc.Err()
+}
+
+// cleanLabels describes each worktree for the picker: branch, why it is
+// recyclable, its last commit and its size on disk, aligned in columns.
+func (e *env) cleanLabels(rs []cycle.Recyclable) []string {
+	sizes := make([]int64, len(rs))
+	var wg sync.WaitGroup
+	for i, r := range rs {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			sizes[i] = dirSize(path)
+		}(i, r.Path)
+	}
+	wg.Wait()
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	for i, r := range rs {
+		reason := "merged into " + e.deps.BaseRef()
+		switch r.PRState {
+		case forge.StateMerged:
+			reason = "PR merged"
+		case forge.StateClosed:
+			reason = "PR closed"
+		}
+		commit, err := e.deps.Git.Run("log", "-1", "--format=%h %s (%cr)", "refs/heads/"+r.Branch)
+		if commit = strings.TrimSpace(commit); err != nil || commit == "" {
+			commit = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Branch, reason, commit, formatSize(sizes[i]))
+	}
+	w.Flush()
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// dirSize sums the sizes of the regular files under path, skipping
+// anything it cannot read.
+func dirSize(path string) int64 {
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
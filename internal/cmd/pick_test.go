@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunPicker(t *testing.T) {
+	labels := []string{"wt-1", "wt-2", "wt-3"}
+	tests := []struct {
+		name string
+		keys string
+		want []bool
+	}{
+		{"confirm all", "\r", []bool{true, true, true}},
+		{"uncheck second", "j \r", []bool{true, false, true}},
+		{"arrow keys wrap", "\x1b[A \x1b[B\x1b[B \r", []bool{true, false, false}},
+		{"toggle all off", "a\r", []bool{false, false, false}},
+		{"toggle all back on", " a\r", []bool{true, true, true}},
+		{"ignores other keys", "xyz\n", []bool{true, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runPicker(strings.NewReader(tt.keys), io.Discard, labels)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunPicker_Cancel(t *testing.T) {
+	for _, keys := range []string{"q", "\x03", " j"} {
+		if _, err := runPicker(strings.NewReader(keys), io.Discard, []string{"wt-1", "wt-2"}); !errors.Is(err, errCancelled) {
+			t.Errorf("keys %q: err = %v, want errCancelled", keys, err)
+		}
+	}
+}
+
+func TestPromptEach(t *testing.T) {
+	var out strings.Builder
+	got, err := promptEach(strings.NewReader("y\nno\nYes\n"), &out, []string{"wt-1", "wt-2", "wt-3", "wt-4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []bool{true, false, true, false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selected = %v, want %v", got, want)
+	}
+	if !strings.Contains(out.String(), "Remove wt-1? [y/N] ") {
+		t.Errorf("prompt = %q", out.String())
+	}
+}
+
+func TestChooseTerminal_NotATerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "answers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("n\ny\n")
+	f.Seek(0, 0)
+	defer f.Close()
+
+	got, err := chooseTerminal(f, io.Discard, []string{"wt-1", "wt-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []bool{false, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selected = %v, want %v", got, want)
+	}
+}
+
+func TestDoClean_Interactive(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+	os.WriteFile(filepath.Join(dir2, "big"), make([]byte, 3000), 0644)
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1", "wt-2"},
+		wtPorcelain: fmt.Sprintf(
+			"worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\n"+
+				"worktree %s\nHEAD def\nbranch refs/heads/wt-2\n\n",
+			dir1, dir2,
+		),
+		cleanPaths: map[string]bool{dir1: true, dir2: true},
+		repoRoot:   dir1,
+	}
+	g.runFn = func(args []string) (string, error) {
+		if args[0] == "log" {
+			return "abc1234 fix things (2 days ago)\n", nil
+		}
+		return "", nil
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	var removed []string
+	e.runWt = func(args ...string) error {
+		removed = append(removed, args[len(args)-1])
+		return nil
+	}
+	var labels []string
+	e.choose = func(l []string) ([]bool, error) {
+		labels = l
+		return []bool{false, true}, nil
+	}
+
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(labels) != 2 {
+		t.Fatalf("labels = %q", labels)
+	}
+	for _, want := range []string{"wt-2", "merged into origin/main", "abc1234 fix things (2 days ago)", "2.9 KiB"} {
+		if !strings.Contains(labels[1], want) {
+			t.Errorf("label %q lacks %q", labels[1], want)
+		}
+	}
+	if !reflect.DeepEqual(removed, []string{"wt-2"}) {
+		t.Errorf("removed %v, want only wt-2", removed)
+	}
+}
+
+func TestDoClean_InteractiveCancelled(t *testing.T) {
+	dir := t.TempDir()
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.runWt = func(args ...string) error {
+		t.Errorf("unexpected wt %v", args)
+		return nil
+	}
+	e.choose = func([]string) ([]bool, error) { return nil, errCancelled }
+
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+	if m := mutations(g); len(m) != 0 {
+		t.Errorf("unexpected git calls %v", m)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 << 30: "5.0 GiB"} {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/worktree"
+)
+
+// planStep is one command a dry run would have run.
+type planStep struct {
+	Tool string   `json:"tool"` // "git", "wt" or "wt-cycle"
+	Args []string `json:"args"`
+	Dir  string   `json:"dir,omitempty"` // working directory it would run in
+}
+
+// planner collects the mutating commands of a dry run in order.
+type planner struct {
+	dir   string // where the process would have chdir'd to
+	steps []planStep
+}
+
+func (p *planner) add(tool string, args ...string) {
+	p.steps = append(p.steps, planStep{Tool: tool, Args: args, Dir: p.dir})
+}
+
+// write prints the plan as a numbered list, or as JSON.
+func (p *planner) write(w io.Writer, jsonOut bool) error {
+	if jsonOut {
+		steps := p.steps
+		if steps == nil {
+			steps = []planStep{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(steps)
+	}
+	if len(p.steps) == 0 {
+		fmt.Fprintln(w, "Nothing to do.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for i, s := range p.steps {
+		fmt.Fprintf(tw, "%d.\t%s %s\t%s\n", i+1, s.Tool, strings.Join(s.Args, " "), s.Dir)
+	}
+	return tw.Flush()
+}
+
+// planGit passes read-only calls through to the real client and records
+// the rest in the plan instead of running them. Stash drops are also
+// applied to what StashList reports, since later decisions depend on them.
+type planGit struct {
+	gitpkg.Client
+	plan    *planner
+	dropped map[string]bool // stash refs the plan drops, e.g. "stash@{0}"
+}
+
+func (g *planGit) Run(args ...string) (string, error) {
+	if gitpkg.IsReadOnly(args) {
+		return g.Client.Run(args...)
+	}
+	g.plan.add("git", args...)
+	if len(args) > 2 && args[0] == "stash" && args[1] == "drop" {
+		if g.dropped == nil {
+			g.dropped = make(map[string]bool)
+		}
+		g.dropped[args[len(args)-1]] = true
+	}
+	return "", nil
+}
+
+// StashList leaves out the stashes the plan has dropped. Their refs keep
+// their original numbering, which is what the plan's drops refer to.
+func (g *planGit) StashList() (string, error) {
+	out, err := g.Client.StashList()
+	if err != nil || len(g.dropped) == 0 {
+		return out, err
+	}
+	var kept []string
+	for _, line := range strings.Split(out, "\n") {
+		ref, _, _ := strings.Cut(line, "\t")
+		if !g.dropped[ref] {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n"), nil
+}
+
+func (g *planGit) UpdateRef(ref, sha string) error {
+	g.plan.add("git", "update-ref", ref, sha)
+	return nil
+}
+
+func (g *planGit) DeleteRef(ref string) error {
+	g.plan.add("git", "update-ref", "-d", ref)
+	return nil
+}
+
+// startDryRun makes e record the git and worktrunk commands it would run
+// instead of running them. Reads, including fetching the base branch, still
+// happen so the plan matches what a real run would decide. Hooks are not
+// run, and only warnings are logged unless --verbose is set, since progress
+// messages would describe work that isn't done.
+func (e *env) startDryRun() *planner {
+	dir, _ := os.Getwd()
+	p := &planner{dir: dir}
+	g := &planGit{Client: e.deps.Git, plan: p}
+	e.deps.Git = g
+	if _, ok := e.backend.(*worktree.Native); ok {
+		e.backend = &worktree.Native{Git: g}
+	}
+	e.runWt = func(args ...string) error {
+		p.add("wt", args...)
+		return nil
+	}
+	e.chdir = func(path string) error {
+		p.dir = path
+		return nil
+	}
+	e.runHook = nil
+	if e.spawn != nil {
+		e.spawn = func(args ...string) error {
+			p.add("wt-cycle", args...)
+			return nil
+		}
+	}
+	logf := e.deps.Logf
+	e.deps.Logf = func(format string, args ...interface{}) {
+		if e.deps.Verbose || strings.HasPrefix(format, "warning:") || strings.HasPrefix(format, "⚠️") {
+			logf(format, args...)
+		}
+	}
+	e.plan = p
+	return p
+}
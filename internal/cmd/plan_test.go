@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/hooks"
+	"github.com/sestinj/wt-cycle/internal/worktree"
+)
+
+var archiveTime = regexp.MustCompile(`(refs/wt-cycle/(?:archive|stash)/[^ ]+)/\d+(?:-\d+)?`)
+
+// stepStrings renders steps as "tool args" for comparison, replacing the
+// timestamp that archive and stash refs end in with T.
+func stepStrings(p *planner) []string {
+	var out []string
+	for _, s := range p.steps {
+		line := s.Tool + " " + strings.Join(s.Args, " ")
+		out = append(out, archiveTime.ReplaceAllString(line, "$1/T"))
+	}
+	return out
+}
+
+func assertSteps(t *testing.T, p *planner, want ...string) {
+	t.Helper()
+	got := stepStrings(p)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("plan:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestDryRun_Clean(t *testing.T) {
+	dir1 := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\n", dir1),
+		cleanPaths:    map[string]bool{dir1: true},
+		repoRoot:      dir1,
+	}
+
+	e, stdout := testEnv(t, g, &mockGH{})
+	wtCalled := false
+	e.runWt = func(args ...string) error {
+		wtCalled = true
+		return nil
+	}
+	hookCalled := false
+	e.runHook = func(hooks.Context) error {
+		hookCalled = true
+		return nil
+	}
+
+	p := e.startDryRun()
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+
+	if wtCalled || hookCalled || len(g.runCalls) != 0 || len(g.updatedRefs) != 0 {
+		t.Errorf("dry run ran something: wt %v, hook %v, git %v, refs %v", wtCalled, hookCalled, g.runCalls, g.updatedRefs)
+	}
+	assertSteps(t, p,
+		"wt remove -y wt-1",
+		"git update-ref refs/wt-cycle/archive/wt-1/T abc",
+		"git branch -D wt-1",
+	)
+	if stdout.Len() != 0 {
+		t.Errorf("doClean wrote to stdout in a dry run: %q", stdout.String())
+	}
+}
+
+func TestDryRun_NextRecycle(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		repoRoot:      dir,
+		refs:          []string{"wt-1"},
+	}
+
+	e, stdout := testEnv(t, g, &mockGH{})
+	chdirCalled := false
+	e.chdir = func(string) error {
+		chdirCalled = true
+		return nil
+	}
+	e.claimOwner = "agent-1"
+
+	p := e.startDryRun()
+	if err := e.doNext(); err != nil {
+		t.Fatal(err)
+	}
+
+	if chdirCalled || len(g.runCalls) != 0 {
+		t.Errorf("dry run ran something: chdir %v, git %v", chdirCalled, g.runCalls)
+	}
+	assertSteps(t, p,
+		"wt switch wt-1",
+		"git checkout -q origin/main",
+		"git update-ref refs/wt-cycle/archive/wt-1/T abc",
+		"git branch -D wt-1",
+		"git checkout -q -b wt-2",
+	)
+	// Commands after the switch run inside the recycled worktree.
+	if got := p.steps[1].Dir; got != dir {
+		t.Errorf("checkout dir = %q, want %q", got, dir)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("dry run printed a path: %q", stdout.String())
+	}
+}
+
+func TestDryRun_NextCreate_JSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+
+	g := &mockGit{
+		currentBranch: "main",
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/main\n\n", repoRoot),
+		repoRoot:      repoRoot,
+	}
+
+	e, stdout := testEnv(t, g, &mockGH{})
+	e.backend = &worktree.Native{Git: g}
+	e.jsonOut = true
+
+	p := e.startDryRun()
+	if err := e.doNext(); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.runCalls) != 0 {
+		t.Errorf("dry run ran git: %v", g.runCalls)
+	}
+	if err := p.write(stdout, e.jsonOut); err != nil {
+		t.Fatal(err)
+	}
+
+	var steps []planStep
+	if err := json.Unmarshal(stdout.Bytes(), &steps); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout.String(), err)
+	}
+	if len(steps) != 1 || steps[0].Tool != "git" {
+		t.Fatalf("steps = %+v, want one git step", steps)
+	}
+	assertArgs(t, steps[0].Args, "worktree", "add", "-q", "-b", "wt-1", filepath.Join(tmpDir, "myrepo.wt-1"), "origin/main")
+}
+
+func TestPlanner_WriteText(t *testing.T) {
+	p := &planner{dir: "/src/repo"}
+	var out strings.Builder
+	p.write(&out, false)
+	if got := out.String(); got != "Nothing to do.\n" {
+		t.Errorf("empty plan = %q", got)
+	}
+
+	p.add("wt", "remove", "-y", "wt-1")
+	p.dir = "/src/repo.wt-2"
+	p.add("git", "branch", "-D", "wt-2")
+	out.Reset()
+	p.write(&out, false)
+	want := "1.  wt remove -y wt-1   /src/repo\n" +
+		"2.  git branch -D wt-2  /src/repo.wt-2\n"
+	if got := out.String(); got != want {
+		t.Errorf("plan text =\n%q\nwant\n%q", got, want)
+	}
+}
+
+// recordingGit runs calls on a mockGit and records the mutating ones the
+// way a dry run plans them.
+type recordingGit struct {
+	*mockGit
+	plan *planner
+}
+
+func (g *recordingGit) Run(args ...string) (string, error) {
+	if !gitpkg.IsReadOnly(args) {
+		g.plan.add("git", args...)
+	}
+	return g.mockGit.Run(args...)
+}
+
+func (g *recordingGit) UpdateRef(ref, sha string) error {
+	g.plan.add("git", "update-ref", ref, sha)
+	return g.mockGit.UpdateRef(ref, sha)
+}
+
+func (g *recordingGit) DeleteRef(ref string) error {
+	g.plan.add("git", "update-ref", "-d", ref)
+	return g.mockGit.DeleteRef(ref)
+}
+
+// TestDryRun_ArchiveStashesMatchesRealRun checks that the plan for clean
+// --archive-stashes lists exactly what a real run then does.
+func TestDryRun_ArchiveStashesMatchesRealRun(t *testing.T) {
+	dir := t.TempDir()
+	newGit := func() *mockGit {
+		g := &mockGit{
+			currentBranch: "main",
+			merged:        []string{"wt-2"},
+			wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-2\n\n", dir),
+			cleanPaths:    map[string]bool{dir: true},
+			repoRoot:      dir,
+			stashList:     "stash@{0}\taaa\taaa-base aaa-index\tWIP on wt-2: abc wip\nstash@{1}\tbbb\tbbb-base bbb-index\tOn main: other\n",
+		}
+		g.runFn = func(args []string) (string, error) {
+			if args[0] == "stash" && args[1] == "drop" {
+				g.stashList = "stash@{0}\tbbb\tbbb-base bbb-index\tOn main: other\n"
+			}
+			return "", nil
+		}
+		return g
+	}
+
+	g := newGit()
+	e, _ := testEnv(t, g, &mockGH{})
+	e.backend = &worktree.Native{Git: g}
+	e.archiveStashes = true
+	planned := e.startDryRun()
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.runCalls) != 0 || len(g.updatedRefs) != 0 {
+		t.Fatalf("dry run ran something: git %v, refs %v", g.runCalls, g.updatedRefs)
+	}
+
+	rec := &recordingGit{mockGit: newGit(), plan: &planner{}}
+	e, _ = testEnv(t, rec.mockGit, &mockGH{})
+	e.deps.Git = rec
+	e.backend = &worktree.Native{Git: rec}
+	e.archiveStashes = true
+	if err := e.doClean(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertSteps(t, planned, stepStrings(rec.plan)...)
+	if len(planned.steps) != 5 {
+		t.Errorf("expected stash archive and drop, branch archive, remove and delete, got %v", stepStrings(planned))
+	}
+}
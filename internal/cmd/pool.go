@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sestinj/wt-cycle/internal/config"
+	"github.com/sestinj/wt-cycle/internal/cycle"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/hooks"
+	"github.com/spf13/cobra"
+)
+
+var poolCmd = &cobra.Command{
+	Use:   "pool",
+	Short: "Manage the pool of pre-warmed worktrees",
+}
+
+var poolWarmCmd = &cobra.Command{
+	Use:   "warm",
+	Short: "Fill the pool with spare worktrees on the latest base",
+	Long: "Keeps --size (or pool.size from config) detached worktrees on a freshly fetched base ref. " +
+		"next hands these out by only creating a branch, then refills the pool in the background.",
+	Args: cobra.NoArgs,
+	RunE: runPoolWarm,
+}
+
+var poolSizeFlag int
+
+func init() {
+	poolWarmCmd.Flags().IntVar(&poolSizeFlag, "size", 0, "number of spare worktrees to keep (default pool.size from config)")
+	poolCmd.AddCommand(poolWarmCmd)
+	rootCmd.AddCommand(poolCmd)
+}
+
+func runPoolWarm(cmd *cobra.Command, args []string) error {
+	gitClient := gitpkg.NewExecClient()
+
+	repoRoot, err := gitClient.RepoRoot()
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	cfg := config.Load(repoRoot)
+	size := poolSizeFlag
+	if !cmd.Flags().Changed("size") {
+		if cfg.Pool.Size == nil {
+			return fmt.Errorf("no pool size set: pass --size or set pool.size in %s", config.RepoFileName)
+		}
+		size = *cfg.Pool.Size
+	}
+	if size < 0 {
+		return fmt.Errorf("invalid pool size %d", size)
+	}
+
+	lk, err := acquireLock(gitClient)
+	if err != nil {
+		return err
+	}
+	defer lk.Release()
+
+	e, err := newEnv(gitClient, repoRoot, cfg)
+	if err != nil {
+		return err
+	}
+	return e.doPoolWarm(size)
+}
+
+// doPoolWarm brings the pool to exactly size slots, all detached at the
+// latest base ref. Existing slots are refreshed, missing ones created and
+// surplus ones removed, unless they have changes.
+func (e *env) doPoolWarm(size int) error {
+	d := e.deps
+	if err := d.Git.FetchBase(d.RemoteName(), d.BranchName()); err != nil {
+		d.Logf("warning: fetching %s: %v", d.BaseRef(), err)
+	}
+
+	mainRoot, err := cycle.MainWorktree(d)
+	if err != nil {
+		return fmt.Errorf("locating main worktree: %w", err)
+	}
+	slots, err := cycle.PoolSlots(d)
+	if err != nil {
+		return err
+	}
+
+	baseRef := d.BaseRef()
+	taken := make(map[int]bool)
+	for i, s := range slots {
+		if i >= size {
+			if clean, err := d.Git.IsClean(s.Path); err != nil || !clean {
+				d.Logf("warning: keeping pool slot %s: it has uncommitted changes", s.Path)
+				continue
+			}
+			if err := e.hook(hooks.PreRemove, "", s.Path); err != nil {
+				d.Logf("warning: keeping pool slot %s: %v", s.Path, err)
+				continue
+			}
+			if _, err := d.Git.Run("worktree", "remove", s.Path); err != nil {
+				d.Logf("warning: removing pool slot %s: %v", s.Path, err)
+			}
+			continue
+		}
+		taken[s.Num] = true
+		if clean, err := d.Git.IsClean(s.Path); err != nil || !clean {
+			d.Logf("warning: not refreshing pool slot %s: it has uncommitted changes", s.Path)
+			continue
+		}
+		if _, err := d.Git.Run("-C", s.Path, "checkout", "-q", "--detach", baseRef); err != nil {
+			d.Logf("warning: refreshing pool slot %s: %v", s.Path, err)
+		}
+	}
+
+	have := min(len(slots), size)
+	for k := 1; have < size; k++ {
+		if taken[k] {
+			continue
+		}
+		path := d.PoolLayout().Path(mainRoot, cycle.PoolSlotName(k))
+		if _, err := os.Stat(path); err == nil {
+			// Something unrelated already lives here; leave it alone.
+			continue
+		}
+		if _, err := d.Git.Run("worktree", "add", "-q", "--detach", path, baseRef); err != nil {
+			return fmt.Errorf("creating pool slot %s: %w", path, err)
+		}
+		if err := e.hook(hooks.PostCreate, "", path); err != nil {
+			d.Logf("warning: %v", err)
+		}
+		have++
+	}
+
+	d.Logf("🔥 Pool ready: %d warm worktree(s) on %s", size, baseRef)
+	return nil
+}
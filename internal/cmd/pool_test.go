@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoPoolWarm_CreatesMissingSlots(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+	os.MkdirAll(repoRoot, 0755)
+
+	g := &mockGit{
+		repoRoot:    repoRoot,
+		wtPorcelain: "worktree " + repoRoot + "\nHEAD abc\nbranch refs/heads/main\n\n",
+	}
+	g.registerDetached(filepath.Join(tmpDir, "myrepo.pool-1"))
+	g.cleanPaths = map[string]bool{filepath.Join(tmpDir, "myrepo.pool-1"): true}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	if err := e.doPoolWarm(3); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.runCalls) != 3 {
+		t.Fatalf("expected 3 git Run calls, got %d: %v", len(g.runCalls), g.runCalls)
+	}
+	slot1 := filepath.Join(tmpDir, "myrepo.pool-1")
+	assertArgs(t, g.runCalls[0], "-C", slot1, "checkout", "-q", "--detach", "origin/main")
+	assertArgs(t, g.runCalls[1], "worktree", "add", "-q", "--detach", filepath.Join(tmpDir, "myrepo.pool-2"), "origin/main")
+	assertArgs(t, g.runCalls[2], "worktree", "add", "-q", "--detach", filepath.Join(tmpDir, "myrepo.pool-3"), "origin/main")
+}
+
+func TestDoPoolWarm_SkipsOccupiedDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+	os.MkdirAll(repoRoot, 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "myrepo.pool-1"), 0755)
+
+	g := &mockGit{repoRoot: repoRoot}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	if err := e.doPoolWarm(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.runCalls) != 1 {
+		t.Fatalf("expected 1 git Run call, got %d: %v", len(g.runCalls), g.runCalls)
+	}
+	assertArgs(t, g.runCalls[0], "worktree", "add", "-q", "--detach", filepath.Join(tmpDir, "myrepo.pool-2"), "origin/main")
+}
+
+func TestDoPoolWarm_RemovesSurplus(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+
+	g := &mockGit{
+		repoRoot:    repoRoot,
+		wtPorcelain: "worktree " + repoRoot + "\nHEAD abc\nbranch refs/heads/main\n\n",
+	}
+	g.registerDetached(filepath.Join(tmpDir, "myrepo.pool-1"))
+	g.registerDetached(filepath.Join(tmpDir, "myrepo.pool-2"))
+	g.registerDetached(filepath.Join(tmpDir, "myrepo.pool-3"))
+	g.cleanPaths = map[string]bool{
+		filepath.Join(tmpDir, "myrepo.pool-1"): true,
+		filepath.Join(tmpDir, "myrepo.pool-2"): true,
+		filepath.Join(tmpDir, "myrepo.pool-3"): false,
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	if err := e.doPoolWarm(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// pool-3 has changes, so it stays.
+	if len(g.runCalls) != 2 {
+		t.Fatalf("expected 2 git Run calls, got %d: %v", len(g.runCalls), g.runCalls)
+	}
+	assertArgs(t, g.runCalls[1], "worktree", "remove", filepath.Join(tmpDir, "myrepo.pool-2"))
+}
+
+func TestDoPoolWarm_LeavesDirtySlots(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+	slot := filepath.Join(tmpDir, "myrepo.pool-1")
+
+	g := &mockGit{
+		repoRoot:    repoRoot,
+		wtPorcelain: "worktree " + repoRoot + "\nHEAD abc\nbranch refs/heads/main\n\n",
+		cleanPaths:  map[string]bool{slot: false},
+	}
+	g.registerDetached(slot)
+
+	e, _ := testEnv(t, g, &mockGH{})
+	if err := e.doPoolWarm(1); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.runCalls) != 0 {
+		t.Fatalf("expected the dirty slot to be left alone, got %v", g.runCalls)
+	}
+}
+
+func TestDoNext_TakesPoolSlot(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+	dirty := filepath.Join(tmpDir, "myrepo.pool-1")
+	slot := filepath.Join(tmpDir, "myrepo.pool-2")
+
+	// Slot 1 has changes, so slot 2 is handed out instead.
+	g := &mockGit{
+		currentBranch: "main",
+		cleanPaths:    map[string]bool{slot: true, dirty: false},
+		repoRoot:      repoRoot,
+		wtPorcelain:   "worktree " + repoRoot + "\nHEAD abc\nbranch refs/heads/main\n\n",
+	}
+	g.registerDetached(dirty)
+	g.registerDetached(slot)
+
+	e, stdout := testEnv(t, g, &mockGH{})
+	e.runWt = func(args ...string) error {
+		t.Fatalf("wt should not be called when a pool slot is available, got %v", args)
+		return nil
+	}
+	var chdirPath string
+	e.chdir = func(path string) error { chdirPath = path; return nil }
+	var spawned []string
+	e.spawn = func(args ...string) error { spawned = args; return nil }
+
+	if err := e.doNext(); err != nil {
+		t.Fatal(err)
+	}
+
+	newPath := filepath.Join(tmpDir, "myrepo.wt-1")
+	if len(g.runCalls) != 2 {
+		t.Fatalf("expected 2 git Run calls, got %d: %v", len(g.runCalls), g.runCalls)
+	}
+	assertArgs(t, g.runCalls[0], "worktree", "move", slot, newPath)
+	assertArgs(t, g.runCalls[1], "checkout", "-q", "-b", "wt-1", "origin/main")
+	if chdirPath != newPath {
+		t.Errorf("chdir = %q, want %q", chdirPath, newPath)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != newPath {
+		t.Errorf("stdout = %q, want %q", got, newPath)
+	}
+	// No configured size: keep the pool at the two slots it had.
+	assertArgs(t, spawned, "pool", "warm", "--size", "2")
+}
+
+func TestDoNext_PoolSlotMoveFails(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+
+	g := &mockGit{
+		currentBranch: "main",
+		cleanPaths:    map[string]bool{filepath.Join(tmpDir, "myrepo.pool-1"): true},
+		repoRoot:      repoRoot,
+		wtPorcelain:   "worktree " + repoRoot + "\nHEAD abc\nbranch refs/heads/main\n\n",
+		runFn: func(args []string) (string, error) {
+			return "", os.ErrPermission
+		},
+	}
+	g.registerDetached(filepath.Join(tmpDir, "myrepo.pool-1"))
+
+	e, _ := testEnv(t, g, &mockGH{})
+	e.poolSize = 4
+	e.spawn = func(args ...string) error {
+		t.Fatalf("should not refill after a failed handout, got %v", args)
+		return nil
+	}
+
+	err := e.doNext()
+	if err == nil || !strings.Contains(err.Error(), "moving pool slot") {
+		t.Fatalf("expected moving pool slot error, got %v", err)
+	}
+}
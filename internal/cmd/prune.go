@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sestinj/wt-cycle/internal/config"
+	"github.com/sestinj/wt-cycle/internal/cycle"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Reconcile branches, worktree entries and directories",
+	Long: "Deletes numbered branches that have no worktree if they are merged or all their commits are on the " +
+		"remote (archiving them first, see restore), prunes worktree entries whose directory is gone, and " +
+		"handles directories at worktree paths that git has no entry for: --orphans=keep reports them, remove " +
+		"deletes them, adopt registers them as worktrees again, keeping their files as uncommitted changes. " +
+		"A directory that was moved by hand is repaired in place by adopt.",
+	Args: cobra.NoArgs,
+	RunE: runPrune,
+}
+
+var (
+	pruneDryRun  bool
+	pruneOrphans string
+)
+
+func init() {
+	pruneCmd.Flags().BoolVarP(&pruneDryRun, "dry-run", "n", false, "print what would be done without changing anything")
+	pruneCmd.Flags().StringVar(&pruneOrphans, "orphans", cycle.OrphansKeep, "what to do with orphaned directories: keep, remove or adopt")
+	rootCmd.AddCommand(pruneCmd)
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	switch pruneOrphans {
+	case cycle.OrphansKeep, cycle.OrphansRemove, cycle.OrphansAdopt:
+	default:
+		return fmt.Errorf("invalid --orphans %q: want keep, remove or adopt", pruneOrphans)
+	}
+
+	gitClient := gitpkg.NewExecClient()
+
+	repoRoot, err := gitClient.RepoRoot()
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	lk, err := acquireLock(gitClient)
+	if err != nil {
+		return err
+	}
+	defer lk.Release()
+
+	e, err := newEnv(gitClient, repoRoot, config.Load(repoRoot))
+	if err != nil {
+		return err
+	}
+	return e.doPrune(pruneOrphans, pruneDryRun)
+}
+
+func (e *env) doPrune(orphans string, dryRun bool) error {
+	plan, err := cycle.PlanPrune(e.deps, orphans)
+	if err != nil {
+		return err
+	}
+	if plan.Empty() {
+		e.deps.Logf("✨ Nothing to prune")
+		return nil
+	}
+
+	// step runs one change, or only describes it in a dry run.
+	step := func(desc string, run func() error) {
+		if dryRun {
+			fmt.Fprintf(e.stdout, "would %s\n", desc)
+			return
+		}
+		e.deps.Logf("🧹 %s", strings.ToUpper(desc[:1])+desc[1:])
+		if err := run(); err != nil {
+			e.deps.Logf("warning: could not %s: %v", desc, err)
+		}
+	}
+
+	keptMoved := false
+	for _, o := range plan.Orphans {
+		switch orphans {
+		case cycle.OrphansKeep:
+			if o.MovedFrom != nil {
+				keptMoved = true
+				e.deps.Logf("⚠️  Keeping %s, which looks like %s moved by hand; use --orphans=adopt to repair it", o.Path, o.MovedFrom.Path)
+			} else {
+				e.deps.Logf("⚠️  Keeping orphaned directory %s; use --orphans=remove or --orphans=adopt", o.Path)
+			}
+		case cycle.OrphansRemove:
+			step("remove orphaned directory "+o.Path, func() error { return os.RemoveAll(o.Path) })
+		case cycle.OrphansAdopt:
+			if o.MovedFrom != nil {
+				step(fmt.Sprintf("repair %s, moved from %s", o.Path, o.MovedFrom.Path), func() error {
+					_, err := e.deps.Git.Run("worktree", "repair", o.Path)
+					return err
+				})
+			} else {
+				step("adopt "+o.Path+" as "+o.Branch, func() error { return e.adoptOrphan(o) })
+			}
+		}
+	}
+
+	if keptMoved {
+		// `git worktree prune` would drop the entries kept for moved
+		// directories, so remove the stale ones one by one.
+		for _, wt := range plan.Missing {
+			step("prune worktree entry "+wt.Path, func() error {
+				_, err := e.deps.Git.Run("worktree", "remove", wt.Path)
+				return err
+			})
+		}
+	} else if len(plan.Missing) > 0 {
+		var paths []string
+		for _, wt := range plan.Missing {
+			paths = append(paths, wt.Path)
+		}
+		step(fmt.Sprintf("prune worktree entries %v", paths), func() error {
+			_, err := e.deps.Git.Run("worktree", "prune")
+			return err
+		})
+	}
+
+	for _, r := range plan.Branches {
+		if dryRun {
+			step("delete dangling branch "+r.Branch, nil)
+			continue
+		}
+		if !e.archiveBranch(r) {
+			continue
+		}
+		step("delete dangling branch "+r.Branch, func() error {
+			_, err := e.deps.Git.Run("branch", "-D", r.Branch)
+			return err
+		})
+	}
+	for _, s := range plan.Kept {
+		why := s.Detail
+		if s.Reason == "closed-unmerged" {
+			why = "its PR was closed without merging"
+		}
+		e.deps.Logf("⚠️  Keeping dangling branch %s: %s", s.Branch, why)
+	}
+	return nil
+}
+
+// adoptOrphan registers o.Path as a worktree of its branch without
+// touching its files, which then show up as uncommitted changes. The
+// branch is created at the base ref if it does not exist; if another
+// worktree has it, the directory gets a detached HEAD instead.
+func (e *env) adoptOrphan(o cycle.Orphan) error {
+	aside := o.Path + ".wt-cycle-adopt"
+	if err := os.Rename(o.Path, aside); err != nil {
+		return err
+	}
+
+	args := []string{"worktree", "add", "-q", "--no-checkout"}
+	if _, err := e.deps.Git.ResolveRef("refs/heads/" + o.Branch); err != nil {
+		args = append(args, "-b", o.Branch, o.Path, e.deps.BaseRef())
+	} else if _, err := cycle.WorktreePath(e.deps, o.Branch); err == nil {
+		args = append(args, "--detach", o.Path, e.deps.BaseRef())
+	} else {
+		args = append(args, o.Path, o.Branch)
+	}
+	if _, err := e.deps.Git.Run(args...); err != nil {
+		if rerr := os.Rename(aside, o.Path); rerr != nil {
+			return fmt.Errorf("%w (the files are at %s)", err, aside)
+		}
+		return err
+	}
+
+	// Swap the new worktree's .git file into the orphan and put it back.
+	if err := os.Rename(filepath.Join(o.Path, ".git"), filepath.Join(aside, ".git")); err != nil {
+		return fmt.Errorf("%w (the files are at %s)", err, aside)
+	}
+	if err := os.Remove(o.Path); err != nil {
+		return fmt.Errorf("%w (the files are at %s)", err, aside)
+	}
+	if err := os.Rename(aside, o.Path); err != nil {
+		return fmt.Errorf("%w (the files are at %s)", err, aside)
+	}
+	// Populate the index from HEAD so only real differences show as changes.
+	_, err := e.deps.Git.Run("-C", o.Path, "reset", "-q")
+	return err
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/cycle"
+)
+
+// pruneRepo sets up myrepo with a missing worktree wt-2, an orphaned
+// directory myrepo.wt-4 and a dangling branch wt-3.
+func pruneRepo(t *testing.T) (*mockGit, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+	orphan := filepath.Join(tmpDir, "myrepo.wt-4")
+	os.MkdirAll(repoRoot, 0755)
+	os.MkdirAll(orphan, 0755)
+	os.WriteFile(filepath.Join(orphan, "notes.txt"), []byte("work"), 0644)
+
+	g := &mockGit{
+		currentBranch: "main",
+		repoRoot:      repoRoot,
+		wtPorcelain: fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/main\n\n"+
+			"worktree %s\nHEAD abc\nbranch refs/heads/wt-2\nprunable gitdir file points to non-existent location\n\n",
+			repoRoot, filepath.Join(tmpDir, "myrepo.wt-2")),
+		refs: []string{"main", "wt-2", "wt-3"},
+		shas: map[string]string{"refs/heads/wt-3": "c3"},
+	}
+	return g, tmpDir
+}
+
+func TestDoPrune_DryRun(t *testing.T) {
+	g, tmpDir := pruneRepo(t)
+	e, stdout := testEnv(t, g, &mockGH{})
+
+	if err := e.doPrune(cycle.OrphansRemove, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.runCalls) != 0 || len(g.updatedRefs) != 0 {
+		t.Errorf("dry run changed the repo: runs %v, refs %v", g.runCalls, g.updatedRefs)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "myrepo.wt-4")); err != nil {
+		t.Errorf("dry run removed the orphan: %v", err)
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"would remove orphaned directory " + filepath.Join(tmpDir, "myrepo.wt-4"),
+		"would prune worktree entries [" + filepath.Join(tmpDir, "myrepo.wt-2") + "]",
+		"would delete dangling branch wt-2",
+		"would delete dangling branch wt-3",
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDoPrune_Remove(t *testing.T) {
+	g, tmpDir := pruneRepo(t)
+	g.refs = []string{"main", "wt-3"}
+	e, _ := testEnv(t, g, &mockGH{})
+
+	if err := e.doPrune(cycle.OrphansRemove, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "myrepo.wt-4")); !os.IsNotExist(err) {
+		t.Errorf("orphan still exists: %v", err)
+	}
+	if len(g.runCalls) != 2 {
+		t.Fatalf("expected 2 git Run calls, got %v", g.runCalls)
+	}
+	assertArgs(t, g.runCalls[0], "worktree", "prune")
+	assertArgs(t, g.runCalls[1], "branch", "-D", "wt-3")
+	if len(g.updatedRefs) != 1 {
+		t.Errorf("expected wt-3 to be archived, got %v", g.updatedRefs)
+	}
+}
+
+func TestDoPrune_KeepsUnpushedBranch(t *testing.T) {
+	g, _ := pruneRepo(t)
+	g.refs = []string{"main", "wt-3"}
+	g.unpushed = map[string]int{"wt-3": 1}
+	e, _ := testEnv(t, g, &mockGH{})
+	var logs []string
+	e.deps.Logf = func(format string, a ...interface{}) { logs = append(logs, fmt.Sprintf(format, a...)) }
+
+	if err := e.doPrune(cycle.OrphansKeep, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range mutations(g) {
+		if c[0] == "branch" {
+			t.Errorf("unpushed branch deleted: %v", c)
+		}
+	}
+	if len(g.updatedRefs) != 0 {
+		t.Errorf("unexpected archive refs %v", g.updatedRefs)
+	}
+	if !strings.Contains(strings.Join(logs, "\n"), "Keeping dangling branch wt-3: 1 commit(s) only in this repo") {
+		t.Errorf("logs = %v", logs)
+	}
+}
+
+func TestDoPrune_Adopt(t *testing.T) {
+	g, tmpDir := pruneRepo(t)
+	orphan := filepath.Join(tmpDir, "myrepo.wt-4")
+	g.runFn = func(args []string) (string, error) {
+		if args[0] == "worktree" && args[1] == "add" {
+			// git creates the directory with just a .git file.
+			os.MkdirAll(orphan, 0755)
+			os.WriteFile(filepath.Join(orphan, ".git"), []byte("gitdir: admin\n"), 0644)
+		}
+		return "", nil
+	}
+	e, _ := testEnv(t, g, &mockGH{})
+
+	if err := e.doPrune(cycle.OrphansAdopt, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.runCalls) < 2 {
+		t.Fatalf("expected worktree add and reset, got %v", g.runCalls)
+	}
+	assertArgs(t, g.runCalls[0], "worktree", "add", "-q", "--no-checkout", "-b", "wt-4", orphan, "origin/main")
+	assertArgs(t, g.runCalls[1], "-C", orphan, "reset", "-q")
+	for _, name := range []string{".git", "notes.txt"} {
+		if _, err := os.Stat(filepath.Join(orphan, name)); err != nil {
+			t.Errorf("adopted directory lacks %s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(orphan + ".wt-cycle-adopt"); !os.IsNotExist(err) {
+		t.Errorf("temporary directory left behind: %v", err)
+	}
+	for _, call := range g.runCalls {
+		if call[0] == "branch" && call[len(call)-1] == "wt-4" {
+			t.Errorf("adopted branch deleted: %v", call)
+		}
+	}
+}
+
+func TestDoPrune_KeepMoved(t *testing.T) {
+	g, tmpDir := pruneRepo(t)
+	// myrepo.wt-4 is myrepo.wt-5, moved by hand.
+	old := filepath.Join(tmpDir, "myrepo.wt-5")
+	admin := filepath.Join(g.repoRoot, ".git", "worktrees", "myrepo.wt-5")
+	os.MkdirAll(admin, 0755)
+	os.WriteFile(filepath.Join(admin, "gitdir"), []byte(filepath.Join(old, ".git")+"\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "myrepo.wt-4", ".git"), []byte("gitdir: "+admin+"\n"), 0644)
+	g.registerWorktree(old, "wt-5")
+	g.refs = append(g.refs, "wt-5")
+
+	var logs []string
+	e, _ := testEnv(t, g, &mockGH{})
+	e.deps.Logf = func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+
+	if err := e.doPrune(cycle.OrphansKeep, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The moved worktree's entry and branch survive; wt-2's entry is
+	// removed on its own rather than by `git worktree prune`.
+	for _, call := range g.runCalls {
+		if call[0] == "worktree" && call[1] == "prune" {
+			t.Errorf("unexpected %v", call)
+		}
+		if call[len(call)-1] == old || call[len(call)-1] == "wt-5" {
+			t.Errorf("touched the moved worktree: %v", call)
+		}
+	}
+	assertArgs(t, g.runCalls[0], "worktree", "remove", filepath.Join(tmpDir, "myrepo.wt-2"))
+	if !strings.Contains(strings.Join(logs, "\n"), "looks like "+old+" moved by hand") {
+		t.Errorf("no hint about the moved worktree in %q", logs)
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sestinj/wt-cycle/internal/archive"
+	"github.com/sestinj/wt-cycle/internal/config"
+	"github.com/sestinj/wt-cycle/internal/cycle"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/hooks"
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <branch|archive-id>",
+	Short: "Recreate a deleted branch and its worktree from the archive",
+	Long: "clean and next archive every branch they delete under refs/wt-cycle/archive/<branch>/<time>. " +
+		"restore recreates the branch from the newest archive of <branch>, or from the archive " +
+		"<branch>/<time>, in a new worktree. Prints the worktree path to stdout.",
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	gitClient := gitpkg.NewExecClient()
+
+	repoRoot, err := gitClient.RepoRoot()
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	lk, err := acquireLock(gitClient)
+	if err != nil {
+		return err
+	}
+	defer lk.Release()
+
+	e, err := newEnv(gitClient, repoRoot, config.Load(repoRoot))
+	if err != nil {
+		return err
+	}
+	return e.doRestore(args[0])
+}
+
+func (e *env) doRestore(name string) error {
+	entry, err := archive.Find(e.deps.Git, name)
+	if err != nil {
+		return err
+	}
+	if _, err := e.deps.Git.ResolveRef("refs/heads/" + entry.Branch); err == nil {
+		return fmt.Errorf("branch %s already exists", entry.Branch)
+	}
+
+	e.deps.Logf("⏪ Restoring %s from %s", entry.Branch, entry.Ref)
+
+	mainRoot, err := cycle.MainWorktree(e.deps)
+	if err != nil {
+		return fmt.Errorf("locating main worktree: %w", err)
+	}
+	plannedPath := e.deps.Layout().Path(mainRoot, entry.Branch)
+	if err := e.worktrees().Create(entry.Branch, entry.SHA, plannedPath); err != nil {
+		return fmt.Errorf("creating worktree %s: %w", entry.Branch, err)
+	}
+	path, err := cycle.WorktreePath(e.deps, entry.Branch)
+	if err != nil {
+		return fmt.Errorf("locating restored worktree %s: %w", entry.Branch, err)
+	}
+
+	if err := e.hook(hooks.PostCreate, entry.Branch, path); err != nil {
+		e.deps.Logf("warning: %v", err)
+	}
+	fmt.Fprintln(e.stdout, path)
+	return nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/worktree"
+)
+
+func TestDoRestore_NewestArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+
+	g := &mockGit{
+		repoRoot:    repoRoot,
+		wtPorcelain: fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/main\n\n", repoRoot),
+		refList: []gitpkg.Ref{
+			{Name: "refs/wt-cycle/archive/wt-3/1700000100", SHA: "old"},
+			{Name: "refs/wt-cycle/archive/wt-3/1700000200", SHA: "new"},
+		},
+	}
+	g.runFn = func(args []string) (string, error) {
+		if args[0] == "worktree" && args[1] == "add" {
+			g.registerWorktree(args[5], args[4])
+		}
+		return "", nil
+	}
+
+	e, stdout := testEnv(t, g, &mockGH{})
+	e.backend = &worktree.Native{Git: g}
+
+	if err := e.doRestore("wt-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	wantPath := filepath.Join(tmpDir, "myrepo.wt-3")
+	if len(g.runCalls) != 1 {
+		t.Fatalf("expected 1 git Run call, got %v", g.runCalls)
+	}
+	assertArgs(t, g.runCalls[0], "worktree", "add", "-q", "-b", "wt-3", wantPath, "new")
+	if got := strings.TrimSpace(stdout.String()); got != wantPath {
+		t.Errorf("stdout = %q, want %q", got, wantPath)
+	}
+}
+
+func TestDoRestore_ByID(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+
+	g := &mockGit{
+		repoRoot:    repoRoot,
+		wtPorcelain: fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/main\n\n", repoRoot),
+		refList: []gitpkg.Ref{
+			{Name: "refs/wt-cycle/archive/wt-3/1700000100", SHA: "old"},
+			{Name: "refs/wt-cycle/archive/wt-3/1700000200", SHA: "new"},
+		},
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	var wtArgs []string
+	e.runWt = func(args ...string) error {
+		wtArgs = args
+		g.registerWorktree(filepath.Join(tmpDir, "myrepo.wt-3"), args[2])
+		return nil
+	}
+
+	if err := e.doRestore("wt-3/1700000100"); err != nil {
+		t.Fatal(err)
+	}
+	assertArgs(t, wtArgs, "switch", "-c", "wt-3", "--base", "old")
+}
+
+func TestDoRestore_BranchExists(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+
+	g := &mockGit{
+		repoRoot:    repoRoot,
+		wtPorcelain: fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/main\n\n", repoRoot),
+		refList:     []gitpkg.Ref{{Name: "refs/wt-cycle/archive/wt-3/1700000100", SHA: "old"}},
+		shas:        map[string]string{"refs/heads/wt-3": "other"},
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+
+	err := e.doRestore("wt-3")
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected 'already exists' error, got %v", err)
+	}
+}
+
+func TestDoRestore_NoArchive(t *testing.T) {
+	g := &mockGit{repoRoot: t.TempDir()}
+	e, _ := testEnv(t, g, &mockGH{})
+
+	if err := e.doRestore("wt-9"); err == nil {
+		t.Fatal("expected error when nothing is archived")
+	}
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"strings"
+
 	"github.com/spf13/cobra"
 )
 
@@ -8,6 +10,9 @@ var (
 	verbose bool
 	noCache bool
 	jsonOut bool
+
+	// invocation names the running subcommand, e.g. "pool warm", for the journal.
+	invocation string
 )
 
 var rootCmd = &cobra.Command{
@@ -15,6 +20,9 @@ var rootCmd = &cobra.Command{
 	Short: "Git worktree lifecycle manager",
 	Long:  "Create, recycle, and clean numbered wt-N worktrees.",
 	SilenceUsage: true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		invocation = strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	},
 }
 
 func init() {
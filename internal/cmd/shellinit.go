@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/sestinj/wt-cycle/internal/config"
+	"github.com/sestinj/wt-cycle/internal/cycle"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init bash|zsh|fish",
+	Short: "Print shell functions that cd into worktrees",
+	Long: "Prints wrapper functions for your shell: wtc-next runs next and cds into the worktree, wtc-cd N cds " +
+		"to worktree N (or a branch, or the main worktree with no argument), and wtc-clean runs clean and " +
+		"returns to the main worktree if the current one was removed. wtc-cd completes worktree names.\n\n" +
+		"  bash: eval \"$(wt-cycle shell-init bash)\"   in ~/.bashrc\n" +
+		"  zsh:  eval \"$(wt-cycle shell-init zsh)\"    in ~/.zshrc\n" +
+		"  fish: wt-cycle shell-init fish | source    in ~/.config/fish/config.fish",
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		script, ok := shellScripts[args[0]]
+		if !ok {
+			return fmt.Errorf("unsupported shell %q (want bash, zsh or fish)", args[0])
+		}
+		_, err := fmt.Fprint(cmd.OutOrStdout(), script)
+		return err
+	},
+}
+
+var pathCmd = &cobra.Command{
+	Use:   "path [N|branch]",
+	Short: "Print the path of a worktree",
+	Long: "Prints the directory of worktree N (per the naming template) or of the worktree on branch. " +
+		"With no argument, prints the main worktree. --list prints the numbered worktrees' branches instead.",
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPath,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		gitClient := gitpkg.NewExecClient()
+		repoRoot, err := gitClient.RepoRoot()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		e, err := newEnv(gitClient, repoRoot, config.Load(repoRoot))
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		names, _ := e.worktreeNames()
+		return names, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+var pathList bool
+
+func init() {
+	pathCmd.Flags().BoolVar(&pathList, "list", false, "print the branches of numbered worktrees, one per line")
+	rootCmd.AddCommand(shellInitCmd)
+	rootCmd.AddCommand(pathCmd)
+}
+
+func runPath(cmd *cobra.Command, args []string) error {
+	gitClient := gitpkg.NewExecClient()
+
+	repoRoot, err := gitClient.RepoRoot()
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	e, err := newEnv(gitClient, repoRoot, config.Load(repoRoot))
+	if err != nil {
+		return err
+	}
+	if pathList {
+		return e.doPathList()
+	}
+	target := ""
+	if len(args) > 0 {
+		target = args[0]
+	}
+	return e.doPath(target)
+}
+
+// doPath prints the worktree for target: a number, a branch, or the main
+// worktree when empty.
+func (e *env) doPath(target string) error {
+	if target == "" {
+		path, err := cycle.MainWorktree(e.deps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(e.stdout, path)
+		return nil
+	}
+	branch := target
+	if n, err := strconv.Atoi(target); err == nil && n >= 0 {
+		branch = e.deps.Names().Branch(n)
+	}
+	path, err := cycle.WorktreePath(e.deps, branch)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(e.stdout, path)
+	return nil
+}
+
+func (e *env) doPathList() error {
+	names, err := e.worktreeNames()
+	if err != nil {
+		return err
+	}
+	for _, n := range names {
+		fmt.Fprintln(e.stdout, n)
+	}
+	return nil
+}
+
+// worktreeNames returns the branches of numbered worktrees, for completion.
+func (e *env) worktreeNames() ([]string, error) {
+	out, err := e.deps.Git.WorktreeListPorcelain()
+	if err != nil {
+		return nil, fmt.Errorf("listing worktrees: %w", err)
+	}
+	names := e.deps.Names()
+	var branches []string
+	for _, wt := range gitpkg.ParseWorktreeList(out) {
+		if names.Num(wt.Branch) >= 0 {
+			branches = append(branches, wt.Branch)
+		}
+	}
+	return branches, nil
+}
+
+// shellScripts holds the wrapper functions printed by shell-init. Each
+// calls the binary through `command` so it never recurses into a function.
+var shellScripts = map[string]string{
+	"bash": bashInit,
+	"zsh":  zshInit,
+	"fish": fishInit,
+}
+
+const bashInit = `# wt-cycle shell integration for bash.
+# Add to ~/.bashrc:  eval "$(wt-cycle shell-init bash)"
+
+wtc-next() {
+    local dir
+    dir="$(command wt-cycle next "$@")" || return
+    cd "$dir"
+}
+
+wtc-cd() {
+    local dir
+    dir="$(command wt-cycle path "$@")" || return
+    cd "$dir"
+}
+
+wtc-clean() {
+    local main rc
+    main="$(command wt-cycle path)" || return
+    command wt-cycle clean "$@"
+    rc=$?
+    [ -d "$PWD" ] || cd "$main"
+    return $rc
+}
+
+_wtc_worktrees() {
+    local cur=${COMP_WORDS[COMP_CWORD]}
+    COMPREPLY=($(compgen -W "$(command wt-cycle path --list 2>/dev/null)" -- "$cur"))
+}
+complete -F _wtc_worktrees wtc-cd
+`
+
+const zshInit = `# wt-cycle shell integration for zsh.
+# Add to ~/.zshrc:  eval "$(wt-cycle shell-init zsh)"
+
+wtc-next() {
+    local dir
+    dir="$(command wt-cycle next "$@")" || return
+    cd "$dir"
+}
+
+wtc-cd() {
+    local dir
+    dir="$(command wt-cycle path "$@")" || return
+    cd "$dir"
+}
+
+wtc-clean() {
+    local main rc
+    main="$(command wt-cycle path)" || return
+    command wt-cycle clean "$@"
+    rc=$?
+    [[ -d "$PWD" ]] || cd "$main"
+    return $rc
+}
+
+_wtc_worktrees() {
+    local -a names
+    names=(${(f)"$(command wt-cycle path --list 2>/dev/null)"})
+    compadd -a names
+}
+if (( $+functions[compdef] )); then
+    compdef _wtc_worktrees wtc-cd
+fi
+`
+
+const fishInit = `# wt-cycle shell integration for fish.
+# Add to ~/.config/fish/config.fish:  wt-cycle shell-init fish | source
+
+function wtc-next --description 'Get a worktree from wt-cycle and cd into it'
+    set -l dir (command wt-cycle next $argv); or return
+    cd $dir
+end
+
+function wtc-cd --description 'cd to worktree N, a branch, or the main worktree'
+    set -l dir (command wt-cycle path $argv); or return
+    cd $dir
+end
+
+function wtc-clean --description 'Run wt-cycle clean, leaving removed worktrees'
+    set -l main (command wt-cycle path); or return
+    command wt-cycle clean $argv
+    set -l rc $status
+    test -d $PWD; or cd $main
+    return $rc
+end
+
+complete -c wtc-cd -f -a '(command wt-cycle path --list 2>/dev/null)'
+`
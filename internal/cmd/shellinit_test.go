@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShellScripts_Content(t *testing.T) {
+	for shell, script := range shellScripts {
+		for _, want := range []string{
+			"wtc-next", "wtc-cd", "wtc-clean",
+			"command wt-cycle next", "command wt-cycle path --list",
+		} {
+			if !strings.Contains(script, want) {
+				t.Errorf("%s script lacks %q", shell, want)
+			}
+		}
+	}
+}
+
+// TestShellScripts_Syntax parses each script with its shell, if installed.
+func TestShellScripts_Syntax(t *testing.T) {
+	checks := map[string][]string{
+		"bash": {"bash", "-n"},
+		"zsh":  {"zsh", "-n"},
+		"fish": {"fish", "--no-execute"},
+	}
+	for shell, argv := range checks {
+		t.Run(shell, func(t *testing.T) {
+			if _, err := exec.LookPath(argv[0]); err != nil {
+				t.Skipf("%s not installed", argv[0])
+			}
+			file := filepath.Join(t.TempDir(), "init."+shell)
+			os.WriteFile(file, []byte(shellScripts[shell]), 0644)
+			if out, err := exec.Command(argv[0], append(argv[1:], file)...).CombinedOutput(); err != nil {
+				t.Errorf("%s: %v\n%s", shell, err, out)
+			}
+		})
+	}
+}
+
+// TestShellInit_Bash runs the bash functions against a stub wt-cycle.
+func TestShellInit_Bash(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	tmpDir := t.TempDir()
+	mainDir := filepath.Join(tmpDir, "myrepo")
+	wtDir := filepath.Join(tmpDir, "myrepo.wt-1")
+	binDir := filepath.Join(tmpDir, "bin")
+	for _, d := range []string{mainDir, wtDir, binDir} {
+		os.MkdirAll(d, 0755)
+	}
+	stub := fmt.Sprintf(`#!/bin/sh
+case "$1 $2" in
+    "next "*) echo %[1]q ;;
+    "path --list") printf 'wt-1\nwt-2\n' ;;
+    "path ") echo %[2]q ;;
+    "path "*) echo %[1]q ;;
+    "clean "*) rm -rf %[1]q ;;
+esac
+`, wtDir, mainDir)
+	os.WriteFile(filepath.Join(binDir, "wt-cycle"), []byte(stub), 0755)
+	initFile := filepath.Join(tmpDir, "init.bash")
+	os.WriteFile(initFile, []byte(bashInit), 0644)
+
+	script := fmt.Sprintf(`source %q
+wtc-next && pwd
+cd / && wtc-cd 1 && pwd
+wtc-clean && pwd
+COMP_WORDS=(wtc-cd wt-); COMP_CWORD=1; _wtc_worktrees; echo "${COMPREPLY[@]}"
+`, initFile)
+	c := exec.Command("bash", "-c", script)
+	c.Dir = tmpDir
+	c.Env = append(os.Environ(), "PATH="+binDir+":"+os.Getenv("PATH"))
+	out, err := c.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+
+	want := []string{wtDir, wtDir, mainDir, "wt-1 wt-2"}
+	if got := strings.Split(strings.TrimSpace(string(out)), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDoPath(t *testing.T) {
+	g := &mockGit{
+		wtPorcelain: "worktree /src/myrepo\nHEAD abc\nbranch refs/heads/main\n\n" +
+			"worktree /src/myrepo.wt-3\nHEAD abc\nbranch refs/heads/wt-3\n\n" +
+			"worktree /src/feature\nHEAD abc\nbranch refs/heads/feature\n\n",
+		repoRoot: "/src/myrepo",
+	}
+	for target, want := range map[string]string{
+		"":        "/src/myrepo",
+		"3":       "/src/myrepo.wt-3",
+		"wt-3":    "/src/myrepo.wt-3",
+		"feature": "/src/feature",
+	} {
+		e, stdout := testEnv(t, g, &mockGH{})
+		if err := e.doPath(target); err != nil {
+			t.Errorf("doPath(%q): %v", target, err)
+			continue
+		}
+		if got := strings.TrimSpace(stdout.String()); got != want {
+			t.Errorf("doPath(%q) = %q, want %q", target, got, want)
+		}
+	}
+
+	e, _ := testEnv(t, g, &mockGH{})
+	if err := e.doPath("9"); err == nil {
+		t.Error("doPath(9): expected an error for a missing worktree")
+	}
+}
+
+func TestDoPathList(t *testing.T) {
+	g := &mockGit{
+		wtPorcelain: "worktree /src/myrepo\nHEAD abc\nbranch refs/heads/main\n\n" +
+			"worktree /src/myrepo.wt-3\nHEAD abc\nbranch refs/heads/wt-3\n\n" +
+			"worktree /src/myrepo.pool-1\nHEAD abc\ndetached\n\n" +
+			"worktree /src/myrepo.wt-7\nHEAD abc\nbranch refs/heads/wt-7\n\n",
+	}
+	e, stdout := testEnv(t, g, &mockGH{})
+	if err := e.doPathList(); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "wt-3\nwt-7\n" {
+		t.Errorf("list = %q", got)
+	}
+}
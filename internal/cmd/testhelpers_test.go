@@ -3,10 +3,14 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 
 	"github.com/sestinj/wt-cycle/internal/cycle"
+	"github.com/sestinj/wt-cycle/internal/forge"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
 )
 
 // mockGit implements git.Client for command-level testing.
@@ -20,18 +24,33 @@ type mockGit struct {
 	refs             []string
 	refsErr          error
 	cleanPaths       map[string]bool // path -> isClean
+	squashed         map[string]bool // branch -> squash/rebase merged
+	unpushed         map[string]int  // branch -> local-only commits
+	stashList        string          // raw `git stash list` output
+	diverged         map[string]bool // PR heads that are not on their branch
 	repoRoot         string
 	repoRootErr      error
+	remoteURL        string
+	shas             map[string]string // ref -> commit, for ResolveRef
+	refList          []gitpkg.Ref      // returned by ListRefs, filtered by prefix
+	updateRefErr     error
 
-	mu       sync.Mutex
-	runCalls [][]string
-	runFn    func(args []string) (string, error)
+	mu          sync.Mutex
+	runCalls    [][]string
+	updatedRefs map[string]string // ref -> sha written by UpdateRef
+	deletedRefs []string
+	runFn       func(args []string) (string, error)
 }
 
-func (m *mockGit) FetchOriginMain() error { return nil }
-func (m *mockGit) MergedBranches(_ string) ([]string, error) {
+func (m *mockGit) FetchBase(_, _ string) error            { return nil }
+func (m *mockGit) DefaultBranch(_ string) (string, error) { return "main", nil }
+func (m *mockGit) RemoteURL(_ string) (string, error)     { return m.remoteURL, nil }
+func (m *mockGit) MergedBranches(_, _ string) ([]string, error) {
 	return m.merged, m.mergedErr
 }
+func (m *mockGit) SquashMerged(_, branch string) (bool, error) {
+	return m.squashed[branch], nil
+}
 func (m *mockGit) WorktreeListPorcelain() (string, error) {
 	return m.wtPorcelain, m.wtPorcelainErr
 }
@@ -44,6 +63,56 @@ func (m *mockGit) CurrentBranch() (string, error) {
 func (m *mockGit) RepoRoot() (string, error) {
 	return m.repoRoot, m.repoRootErr
 }
+
+func (m *mockGit) IsAncestor(commit, _ string) (bool, error) {
+	return !m.diverged[commit], nil
+}
+
+// ResolveRef looks ref up in shas. Branches checked out in a worktree of
+// wtPorcelain resolve to "abc" unless listed there.
+func (m *mockGit) ResolveRef(ref string) (string, error) {
+	if sha, ok := m.shas[ref]; ok {
+		return sha, nil
+	}
+	if strings.HasPrefix(ref, "refs/heads/") && strings.Contains(m.wtPorcelain, "branch "+ref+"\n") {
+		return "abc", nil
+	}
+	return "", fmt.Errorf("unknown ref: %s", ref)
+}
+func (m *mockGit) ListRefs(prefixes ...string) ([]gitpkg.Ref, error) {
+	var refs []gitpkg.Ref
+	for _, r := range m.refList {
+		for _, p := range prefixes {
+			if strings.HasPrefix(r.Name, p) {
+				refs = append(refs, r)
+				break
+			}
+		}
+	}
+	return refs, nil
+}
+func (m *mockGit) DeleteRef(ref string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deletedRefs = append(m.deletedRefs, ref)
+	return nil
+}
+func (m *mockGit) UnpushedCommits(branch string, _ ...string) (int, error) {
+	return m.unpushed[branch], nil
+}
+func (m *mockGit) StashList() (string, error) { return m.stashList, nil }
+func (m *mockGit) UpdateRef(ref, sha string) error {
+	if m.updateRefErr != nil {
+		return m.updateRefErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.updatedRefs == nil {
+		m.updatedRefs = make(map[string]string)
+	}
+	m.updatedRefs[ref] = sha
+	return nil
+}
 func (m *mockGit) IsClean(path string) (bool, error) {
 	clean, ok := m.cleanPaths[path]
 	if !ok {
@@ -51,6 +120,18 @@ func (m *mockGit) IsClean(path string) (bool, error) {
 	}
 	return clean, nil
 }
+
+// registerWorktree appends a worktree entry to the porcelain output, as if
+// a backend had just created it.
+func (m *mockGit) registerWorktree(path, branch string) {
+	m.wtPorcelain += fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/%s\n\n", path, branch)
+}
+
+// registerDetached appends a detached-HEAD worktree entry, such as a pool slot.
+func (m *mockGit) registerDetached(path string) {
+	m.wtPorcelain += fmt.Sprintf("worktree %s\nHEAD abc\ndetached\n\n", path)
+}
+
 func (m *mockGit) Run(args ...string) (string, error) {
 	m.mu.Lock()
 	m.runCalls = append(m.runCalls, args)
@@ -61,13 +142,20 @@ func (m *mockGit) Run(args ...string) (string, error) {
 	return "", nil
 }
 
-// mockGH implements github.Client for testing.
+// mockGH implements forge.Provider for testing.
 type mockGH struct {
-	branches []string
+	branches []string   // reported as merged PRs
+	prs      []forge.PR // reported as-is, after branches
 	err      error
 }
 
-func (m *mockGH) ClosedPRBranches() ([]string, error) { return m.branches, m.err }
+func (m *mockGH) ClosedPRs() ([]forge.PR, error) {
+	var prs []forge.PR
+	for _, b := range m.branches {
+		prs = append(prs, forge.PR{Branch: b, State: forge.StateMerged, HeadSHA: "head-" + b})
+	}
+	return append(prs, m.prs...), m.err
+}
 
 func nopLogf(string, ...interface{}) {}
 
@@ -79,16 +167,32 @@ func testEnv(t *testing.T, g *mockGit, gh *mockGH) (*env, *bytes.Buffer) {
 	return &env{
 		repoRoot: g.repoRoot,
 		deps: &cycle.Deps{
-			Git:    g,
-			GitHub: gh,
-			Logf:   nopLogf,
+			Git:   g,
+			Forge: gh,
+			Logf:  nopLogf,
+		},
+		runWt: func(args ...string) error {
+			// Mimic `wt switch -c`, which creates the worktree at
+			// worktrunk's default <parent>/<repo>.<branch> location.
+			if len(args) >= 3 && args[0] == "switch" && args[1] == "-c" {
+				g.registerWorktree(worktrunkPath(g.repoRoot, args[2]), args[2])
+			}
+			return nil
 		},
-		runWt: func(args ...string) error { return nil },
-		chdir: func(path string) error { return nil },
+		chdir:  func(path string) error { return nil },
 		stdout: &stdout,
 	}, &stdout
 }
 
+// worktrunkPath returns where worktrunk's default config would put branch.
+func worktrunkPath(repoRoot, branch string) string {
+	base := filepath.Base(repoRoot)
+	if idx := strings.Index(base, ".wt-"); idx != -1 {
+		base = base[:idx]
+	}
+	return filepath.Join(filepath.Dir(repoRoot), base+"."+strings.ReplaceAll(branch, "/", "-"))
+}
+
 // assertArgs verifies that a slice of args matches expected values.
 func assertArgs(t *testing.T, got []string, want ...string) {
 	t.Helper()
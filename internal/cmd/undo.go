@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sestinj/wt-cycle/internal/config"
+	"github.com/sestinj/wt-cycle/internal/cycle"
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/hooks"
+	"github.com/sestinj/wt-cycle/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Reverse the most recent clean or recycle",
+	Long: "Uses the journal (see history) to bring back the worktrees and branches removed by the last clean, " +
+		"or to put a recycled worktree back on its old branch. A recycle is only undone while the new branch " +
+		"has no commits or uncommitted changes of its own. Hooks that already ran are not reversed.",
+	Args: cobra.NoArgs,
+	RunE: runUndo,
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	gitClient := gitpkg.NewExecClient()
+
+	repoRoot, err := gitClient.RepoRoot()
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	lk, err := acquireLock(gitClient)
+	if err != nil {
+		return err
+	}
+	defer lk.Release()
+
+	e, err := newEnv(gitClient, repoRoot, config.Load(repoRoot))
+	if err != nil {
+		return err
+	}
+	return e.doUndo()
+}
+
+// recycle is a worktree moved from one branch to another by next.
+type recycle struct {
+	Path           string
+	OldBranch, Old string // branch and tip before the recycle
+	NewBranch, New string // branch and tip it was given
+}
+
+func (e *env) doUndo() error {
+	ops, err := e.journalOps()
+	if err != nil {
+		return err
+	}
+
+	undone := make(map[string]bool)
+	for _, op := range ops {
+		if op.Undoes != "" {
+			undone[op.Undoes] = true
+		}
+	}
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		if op.Undoes != "" || undone[op.ID] {
+			continue
+		}
+		switch op.Command {
+		case "clean":
+			if removed := removedWorktrees(op); len(removed) > 0 {
+				e.journal.Undoes = op.ID
+				return e.undoClean(removed)
+			}
+		case "next":
+			if r, ok := recycleOf(op); ok {
+				e.journal.Undoes = op.ID
+				return e.undoRecycle(r)
+			}
+		}
+	}
+	return fmt.Errorf("nothing to undo: no clean or recycle in the journal")
+}
+
+// removedWorktrees returns the journal entries for worktrees op removed.
+func removedWorktrees(op journal.Op) []journal.Entry {
+	var removed []journal.Entry
+	for _, en := range op.Entries {
+		if en.Error != "" || en.Branch == "" || en.OldSHA == "" || len(en.Args) < 2 {
+			continue
+		}
+		_, args := gitpkg.SplitDir(en.Args)
+		isGit := en.Tool == journal.ToolGit && args[0] == "worktree" && args[1] == "remove"
+		isWt := en.Tool == journal.ToolWt && args[0] == "remove"
+		if isGit || isWt {
+			removed = append(removed, en)
+		}
+	}
+	return removed
+}
+
+// recycleOf finds the branch deletion and checkout that recycleWorktree
+// performs inside the recycled worktree.
+func recycleOf(op journal.Op) (recycle, bool) {
+	var r recycle
+	for _, en := range op.Entries {
+		_, args := gitpkg.SplitDir(en.Args)
+		if en.Error != "" || en.Tool != journal.ToolGit || len(args) == 0 {
+			continue
+		}
+		switch {
+		case len(args) >= 3 && args[0] == "branch" && args[1] == "-D" && en.OldSHA != "":
+			r.Path, r.OldBranch, r.Old = en.Dir, en.Branch, en.OldSHA
+		case args[0] == "checkout" && en.Branch != "" && r.OldBranch != "" && en.Path == r.Path:
+			r.NewBranch, r.New = en.Branch, en.NewSHA
+			return r, true
+		}
+	}
+	return recycle{}, false
+}
+
+func (e *env) undoClean(removed []journal.Entry) error {
+	for _, en := range removed {
+		if _, err := e.deps.Git.ResolveRef("refs/heads/" + en.Branch); err == nil {
+			if _, err := cycle.WorktreePath(e.deps, en.Branch); err == nil {
+				e.deps.Logf("warning: %s already has a worktree, skipping", en.Branch)
+				continue
+			}
+			if _, err := e.deps.Git.Run("worktree", "add", "-q", en.Path, en.Branch); err != nil {
+				return fmt.Errorf("restoring worktree %s: %w", en.Branch, err)
+			}
+		} else if err := e.worktrees().Create(en.Branch, en.OldSHA, en.Path); err != nil {
+			return fmt.Errorf("restoring %s: %w", en.Branch, err)
+		}
+
+		path, err := cycle.WorktreePath(e.deps, en.Branch)
+		if err != nil {
+			return fmt.Errorf("locating restored worktree %s: %w", en.Branch, err)
+		}
+		if err := e.hook(hooks.PostCreate, en.Branch, path); err != nil {
+			e.deps.Logf("warning: %v", err)
+		}
+		e.deps.Logf("↩️  Restored %s at %s", en.Branch, path)
+	}
+	return nil
+}
+
+func (e *env) undoRecycle(r recycle) error {
+	if e.deps.Leases != nil {
+		if l, err := e.deps.Leases.Active(r.NewBranch); err == nil && l != nil {
+			return fmt.Errorf("cannot undo recycle: %s is leased by %s", r.NewBranch, l.Describe())
+		}
+	}
+	if _, err := e.deps.Git.ResolveRef("refs/heads/" + r.OldBranch); err == nil {
+		return fmt.Errorf("cannot undo recycle: branch %s exists again", r.OldBranch)
+	}
+	tip, err := e.deps.Git.ResolveRef("refs/heads/" + r.NewBranch)
+	if err != nil {
+		return fmt.Errorf("cannot undo recycle: branch %s no longer exists", r.NewBranch)
+	}
+	if tip != r.New {
+		return fmt.Errorf("cannot undo recycle: %s has new commits", r.NewBranch)
+	}
+	clean, err := e.deps.Git.IsClean(r.Path)
+	if err != nil {
+		return fmt.Errorf("cannot undo recycle: %w", err)
+	}
+	if !clean {
+		return fmt.Errorf("cannot undo recycle: %s has uncommitted changes", r.Path)
+	}
+
+	if _, err := e.deps.Git.Run("-C", r.Path, "checkout", "-q", "-b", r.OldBranch, r.Old); err != nil {
+		return fmt.Errorf("checkout -b %s: %w", r.OldBranch, err)
+	}
+	if _, err := e.deps.Git.Run("branch", "-D", r.NewBranch); err != nil {
+		e.deps.Logf("warning: could not delete branch %s: %v", r.NewBranch, err)
+	}
+	e.deps.Logf("↩️  %s is back on %s", r.Path, r.OldBranch)
+	return nil
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gitpkg "github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/journal"
+	"github.com/sestinj/wt-cycle/internal/worktree"
+)
+
+// journalEnv returns a test env whose journal lives in a temp dir, and a
+// function that appends entries to it as if written by command.
+func journalEnv(t *testing.T, g *mockGit) (*env, func(command string, entries ...journal.Entry)) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), journal.FileName)
+	e, _ := testEnv(t, g, &mockGH{})
+	e.journal = journal.Open(path, "undo")
+	e.deps.Git = &journal.Git{Client: g, Journal: e.journal}
+	return e, func(command string, entries ...journal.Entry) {
+		j := journal.Open(path, command)
+		for _, en := range entries {
+			if err := j.Append(en); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// mutations returns g's Run calls minus the read-only lookups made by
+// the journal wrapper.
+func mutations(g *mockGit) [][]string {
+	var calls [][]string
+	for _, c := range g.runCalls {
+		if !gitpkg.IsReadOnly(c) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+func TestDoUndo_Clean(t *testing.T) {
+	repoRoot := filepath.Join(t.TempDir(), "myrepo")
+	g := &mockGit{
+		repoRoot:    repoRoot,
+		wtPorcelain: fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/main\n\n", repoRoot),
+	}
+	g.runFn = func(args []string) (string, error) {
+		if len(args) > 5 && args[0] == "worktree" && args[1] == "add" {
+			g.registerWorktree(args[5], args[4])
+		}
+		return "", nil
+	}
+	e, write := journalEnv(t, g)
+	e.backend = &worktree.Native{Git: e.deps.Git}
+
+	wtPath := filepath.Join(filepath.Dir(repoRoot), "myrepo.wt-2")
+	write("clean",
+		journal.Entry{Tool: journal.ToolGit, Args: []string{"worktree", "remove", wtPath}, Path: wtPath, Branch: "wt-2", OldSHA: "abc123"},
+		journal.Entry{Tool: journal.ToolGit, Args: []string{"update-ref", "refs/wt-cycle/archive/wt-2/1", "abc123"}},
+		journal.Entry{Tool: journal.ToolGit, Args: []string{"branch", "-D", "wt-2"}, Branch: "wt-2", OldSHA: "abc123"},
+	)
+
+	if err := e.doUndo(); err != nil {
+		t.Fatal(err)
+	}
+	calls := mutations(g)
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 git Run call, got %v", calls)
+	}
+	assertArgs(t, calls[0], "worktree", "add", "-q", "-b", "wt-2", wtPath, "abc123")
+
+	// The undo is journaled, so a second undo has nothing left to reverse.
+	if err := e.doUndo(); err == nil || !strings.Contains(err.Error(), "nothing to undo") {
+		t.Errorf("second undo: got %v, want 'nothing to undo'", err)
+	}
+}
+
+func TestDoUndo_Recycle(t *testing.T) {
+	dir := t.TempDir()
+	g := &mockGit{
+		repoRoot:   dir,
+		cleanPaths: map[string]bool{dir: true},
+		shas:       map[string]string{"refs/heads/wt-4": "base"},
+	}
+	e, write := journalEnv(t, g)
+
+	write("next",
+		journal.Entry{Tool: journal.ToolGit, Args: []string{"checkout", "-q", "origin/main"}, Dir: dir, Path: dir, OldSHA: "old", NewSHA: "base"},
+		journal.Entry{Tool: journal.ToolGit, Args: []string{"branch", "-D", "wt-1"}, Dir: dir, Branch: "wt-1", OldSHA: "old"},
+		journal.Entry{Tool: journal.ToolGit, Args: []string{"checkout", "-q", "-b", "wt-4"}, Dir: dir, Path: dir, Branch: "wt-4", OldSHA: "base", NewSHA: "base"},
+	)
+
+	if err := e.doUndo(); err != nil {
+		t.Fatal(err)
+	}
+	calls := mutations(g)
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 git Run calls, got %v", calls)
+	}
+	assertArgs(t, calls[0], "-C", dir, "checkout", "-q", "-b", "wt-1", "old")
+	assertArgs(t, calls[1], "branch", "-D", "wt-4")
+}
+
+func TestDoUndo_RecycleWithNewCommits(t *testing.T) {
+	dir := t.TempDir()
+	g := &mockGit{
+		repoRoot:   dir,
+		cleanPaths: map[string]bool{dir: true},
+		shas:       map[string]string{"refs/heads/wt-4": "moved-on"},
+	}
+	e, write := journalEnv(t, g)
+
+	write("next",
+		journal.Entry{Tool: journal.ToolGit, Args: []string{"branch", "-D", "wt-1"}, Dir: dir, Branch: "wt-1", OldSHA: "old"},
+		journal.Entry{Tool: journal.ToolGit, Args: []string{"checkout", "-q", "-b", "wt-4"}, Dir: dir, Path: dir, Branch: "wt-4", NewSHA: "base"},
+	)
+
+	err := e.doUndo()
+	if err == nil || !strings.Contains(err.Error(), "new commits") {
+		t.Fatalf("expected 'new commits' error, got %v", err)
+	}
+	if len(g.runCalls) != 0 {
+		t.Errorf("nothing should run, got %v", g.runCalls)
+	}
+}
+
+func TestDoUndo_SkipsOtherCommands(t *testing.T) {
+	g := &mockGit{repoRoot: t.TempDir()}
+	e, write := journalEnv(t, g)
+
+	write("pool warm", journal.Entry{Tool: journal.ToolGit, Args: []string{"worktree", "remove", "--force", "/x"}, Path: "/x", OldSHA: "abc"})
+	write("next", journal.Entry{Tool: journal.ToolWt, Args: []string{"switch", "-c", "wt-1"}, Branch: "wt-1"})
+
+	if err := e.doUndo(); err == nil {
+		t.Fatal("expected nothing to undo")
+	}
+}
@@ -6,26 +6,211 @@ import (
 	"path/filepath"
 )
 
-// Config holds user configuration from ~/.config/wt-cycle/config.json.
+// RepoFileName is the per-repository config file, checked in at the repo root.
+const RepoFileName = ".wt-cycle.json"
+
+// Config holds user configuration. The global file at
+// ~/.config/wt-cycle/config.json is loaded first, then the repository's
+// .wt-cycle.json is layered over it (see Merge for precedence).
 type Config struct {
 	Skip []string `json:"skip"`
+
+	// Remote is the git remote worktrees track. Defaults to "origin".
+	Remote string `json:"remote,omitempty"`
+	// BaseBranch is the branch new worktrees start from and are merged into.
+	// When empty it is detected from refs/remotes/<remote>/HEAD.
+	BaseBranch string `json:"base_branch,omitempty"`
+	// Naming is the branch naming template, e.g. "agent/{user}/{n}".
+	// Defaults to "wt-{n}".
+	Naming string `json:"naming,omitempty"`
+	// Backend selects the worktree tool: "worktrunk" or "git". When empty,
+	// worktrunk is used if `wt` is on PATH and plain git otherwise.
+	Backend string `json:"backend,omitempty"`
+	// PathTemplate places worktree directories, e.g. "{parent}/{repo}.{branch}".
+	// It should match worktrunk's own worktree-path setting when using that backend.
+	PathTemplate string `json:"path_template,omitempty"`
+	// InUseCheck controls how worktrees used by running processes are
+	// detected: "cwd" (default), "files" (cwd and open files) or "off".
+	InUseCheck string `json:"in_use_check,omitempty"`
+	// ClosedPRPolicy decides what happens to branches whose PR was closed
+	// without merging: "recycle" (default), "archive" (pin the branch tip
+	// under refs/wt-cycle/pinned/ so it is never pruned) or "keep" (never
+	// recycle).
+	ClosedPRPolicy string `json:"closed_pr_policy,omitempty"`
+	// Pool configures pre-warmed spare worktrees.
+	Pool Pool `json:"pool,omitempty"`
+	// Hooks are shell commands run inside a worktree at lifecycle points.
+	Hooks Hooks `json:"hooks,omitempty"`
+	// Forge selects where closed pull request data comes from.
+	Forge Forge `json:"forge,omitempty"`
+	// Archive configures the refs kept for deleted branches.
+	Archive Archive `json:"archive,omitempty"`
+}
+
+// Archive configures archived branch tips.
+type Archive struct {
+	// RetentionDays is how long `clean` keeps archived tips of deleted
+	// branches. Zero means the default of 30 days; negative keeps them forever.
+	RetentionDays int `json:"retention_days,omitempty"`
+}
+
+// Forge configures the pull request provider.
+type Forge struct {
+	// Provider is "gh" (the gh CLI), "github" (the REST API using
+	// GITHUB_TOKEN or GH_TOKEN), "gitlab" (using GITLAB_TOKEN) or "gitea"
+	// (using GITEA_TOKEN). When empty it is detected from the remote URL.
+	Provider string `json:"provider,omitempty"`
+	// APIURL overrides the API endpoint, e.g. for GitHub Enterprise or a
+	// self-hosted GitLab. APIURL, Host and Token are only read from the
+	// global config.
+	APIURL string `json:"api_url,omitempty"`
+	// Host is the forge's host name when it differs from the remote's,
+	// e.g. for a mirror cloned over a different address. It is used for
+	// provider detection and to derive the API endpoint.
+	Host string `json:"host,omitempty"`
+	// Token overrides the provider's token environment variable.
+	Token string `json:"token,omitempty"`
 }
 
-// Load reads the config file. Returns zero-value Config on any error.
-func Load() Config {
+// Hooks configures lifecycle hooks. Each command runs with `sh -c` in the
+// worktree directory.
+type Hooks struct {
+	PostCreate  string `json:"post_create,omitempty"`
+	PostRecycle string `json:"post_recycle,omitempty"`
+	PreRemove   string `json:"pre_remove,omitempty"`
+	// Timeout is a Go duration such as "90s". Defaults to 10m.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// Pool configures the warm worktree pool.
+type Pool struct {
+	// Size is how many detached spare worktrees `pool warm` keeps ready.
+	// Zero disables the pool. It is a pointer so that a repo file can set
+	// 0 over a global size; nil means unset.
+	Size *int `json:"size,omitempty"`
+}
+
+// SizeOrZero returns the configured size, or 0 if none is set.
+func (p Pool) SizeOrZero() int {
+	if p.Size == nil {
+		return 0
+	}
+	return *p.Size
+}
+
+// GlobalPath returns the path of the user-global config file.
+func GlobalPath() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return Config{}
+		return ""
+	}
+	return filepath.Join(home, ".config", "wt-cycle", "config.json")
+}
+
+// Load reads the global config and, if repoRoot is non-empty, the repo's
+// .wt-cycle.json merged over it. Missing or malformed files are ignored.
+func Load(repoRoot string) Config {
+	cfg, _ := ReadFile(GlobalPath())
+	if repoRoot != "" {
+		repoCfg, _ := ReadFile(filepath.Join(repoRoot, RepoFileName))
+		repoCfg, _ = repoCfg.DropGlobalOnly()
+		cfg = cfg.Merge(repoCfg)
 	}
-	data, err := os.ReadFile(filepath.Join(home, ".config", "wt-cycle", "config.json"))
+	return cfg
+}
+
+// ReadFile parses a single config file. A zero Config is returned with the error.
+func ReadFile(path string) (Config, error) {
+	if path == "" {
+		return Config{}, os.ErrNotExist
+	}
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return Config{}
+		return Config{}, err
 	}
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
-		return Config{}
+		return Config{}, err
 	}
-	return cfg
+	return cfg, nil
+}
+
+// Merge returns c with over layered on top. Scalar settings in over win
+// when set; list settings are concatenated.
+func (c Config) Merge(over Config) Config {
+	merged := c
+	merged.Skip = append(append([]string(nil), c.Skip...), over.Skip...)
+	if over.Remote != "" {
+		merged.Remote = over.Remote
+	}
+	if over.BaseBranch != "" {
+		merged.BaseBranch = over.BaseBranch
+	}
+	if over.Naming != "" {
+		merged.Naming = over.Naming
+	}
+	if over.Backend != "" {
+		merged.Backend = over.Backend
+	}
+	if over.PathTemplate != "" {
+		merged.PathTemplate = over.PathTemplate
+	}
+	if over.InUseCheck != "" {
+		merged.InUseCheck = over.InUseCheck
+	}
+	if over.ClosedPRPolicy != "" {
+		merged.ClosedPRPolicy = over.ClosedPRPolicy
+	}
+	if over.Pool.Size != nil {
+		merged.Pool.Size = over.Pool.Size
+	}
+	if over.Hooks.PostCreate != "" {
+		merged.Hooks.PostCreate = over.Hooks.PostCreate
+	}
+	if over.Hooks.PostRecycle != "" {
+		merged.Hooks.PostRecycle = over.Hooks.PostRecycle
+	}
+	if over.Hooks.PreRemove != "" {
+		merged.Hooks.PreRemove = over.Hooks.PreRemove
+	}
+	if over.Hooks.Timeout != "" {
+		merged.Hooks.Timeout = over.Hooks.Timeout
+	}
+	if over.Forge.Provider != "" {
+		merged.Forge.Provider = over.Forge.Provider
+	}
+	if over.Forge.APIURL != "" {
+		merged.Forge.APIURL = over.Forge.APIURL
+	}
+	if over.Forge.Host != "" {
+		merged.Forge.Host = over.Forge.Host
+	}
+	if over.Forge.Token != "" {
+		merged.Forge.Token = over.Forge.Token
+	}
+	if over.Archive.RetentionDays != 0 {
+		merged.Archive.RetentionDays = over.Archive.RetentionDays
+	}
+	return merged
+}
+
+// DropGlobalOnly returns c without the settings only the global config
+// may set, and the names of those that were set. A checked-in
+// .wt-cycle.json could otherwise send the forge token from the environment
+// to any host it names.
+func (c Config) DropGlobalOnly() (Config, []string) {
+	var dropped []string
+	if c.Forge.APIURL != "" {
+		dropped = append(dropped, "forge.api_url")
+	}
+	if c.Forge.Host != "" {
+		dropped = append(dropped, "forge.host")
+	}
+	if c.Forge.Token != "" {
+		dropped = append(dropped, "forge.token")
+	}
+	c.Forge.APIURL, c.Forge.Host, c.Forge.Token = "", "", ""
+	return c, dropped
 }
 
 // ShouldSkip returns true if the given repo root is in the skip list.
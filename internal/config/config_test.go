@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeConfigs sets HOME to a temp dir and writes the given global and repo
+// config contents (skipped when empty). Returns the repo root.
+func writeConfigs(t *testing.T, global, repo string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if global != "" {
+		dir := filepath.Join(home, ".config", "wt-cycle")
+		os.MkdirAll(dir, 0755)
+		if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(global), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repoRoot := t.TempDir()
+	if repo != "" {
+		if err := os.WriteFile(filepath.Join(repoRoot, RepoFileName), []byte(repo), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repoRoot
+}
+
+func TestLoad_GlobalOnly(t *testing.T) {
+	repoRoot := writeConfigs(t, `{"skip": ["/a"], "base_branch": "trunk"}`, "")
+
+	cfg := Load(repoRoot)
+	if cfg.BaseBranch != "trunk" {
+		t.Errorf("BaseBranch = %q, want trunk", cfg.BaseBranch)
+	}
+	if !cfg.ShouldSkip("/a") {
+		t.Error("expected /a to be skipped")
+	}
+}
+
+func TestLoad_RepoOverridesGlobal(t *testing.T) {
+	repoRoot := writeConfigs(t,
+		`{"skip": ["/a"], "remote": "upstream", "base_branch": "main"}`,
+		`{"skip": ["/b"], "base_branch": "develop"}`,
+	)
+
+	cfg := Load(repoRoot)
+	if cfg.BaseBranch != "develop" {
+		t.Errorf("BaseBranch = %q, want develop (repo wins)", cfg.BaseBranch)
+	}
+	if cfg.Remote != "upstream" {
+		t.Errorf("Remote = %q, want upstream (inherited from global)", cfg.Remote)
+	}
+	if !cfg.ShouldSkip("/a") || !cfg.ShouldSkip("/b") {
+		t.Errorf("Skip = %v, want both /a and /b", cfg.Skip)
+	}
+}
+
+func TestLoad_RepoCannotRedirectForge(t *testing.T) {
+	repoRoot := writeConfigs(t,
+		`{"forge": {"api_url": "https://ghe.example.com/api/v3", "token": "global"}}`,
+		`{"forge": {"provider": "gitlab", "api_url": "https://evil.example.com", "host": "evil.example.com", "token": "repo"}}`,
+	)
+
+	f := Load(repoRoot).Forge
+	if f.Provider != "gitlab" {
+		t.Errorf("Provider = %q, want gitlab (repo may choose the provider)", f.Provider)
+	}
+	if f.APIURL != "https://ghe.example.com/api/v3" || f.Host != "" || f.Token != "global" {
+		t.Errorf("Forge = %+v, want api_url, host and token from the global config only", f)
+	}
+}
+
+func TestLoad_RepoDisablesPool(t *testing.T) {
+	repoRoot := writeConfigs(t, `{"pool": {"size": 3}}`, `{"pool": {"size": 0}}`)
+	if p := Load(repoRoot).Pool; p.Size == nil || p.SizeOrZero() != 0 {
+		t.Errorf("Pool.Size = %v, want 0 from the repo file", p.Size)
+	}
+
+	repoRoot = writeConfigs(t, `{"pool": {"size": 3}}`, `{"base_branch": "develop"}`)
+	if got := Load(repoRoot).Pool.SizeOrZero(); got != 3 {
+		t.Errorf("Pool.Size = %d, want 3 from the global file", got)
+	}
+}
+
+func TestLoad_MalformedRepoFileIgnored(t *testing.T) {
+	repoRoot := writeConfigs(t, `{"base_branch": "trunk"}`, `not json`)
+
+	cfg := Load(repoRoot)
+	if cfg.BaseBranch != "trunk" {
+		t.Errorf("BaseBranch = %q, want trunk", cfg.BaseBranch)
+	}
+}
+
+func TestLoad_NoFiles(t *testing.T) {
+	repoRoot := writeConfigs(t, "", "")
+
+	cfg := Load(repoRoot)
+	if len(cfg.Skip) != 0 || cfg.Remote != "" || cfg.BaseBranch != "" {
+		t.Errorf("expected zero config, got %+v", cfg)
+	}
+}
+
+func TestReadFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"skip": 1}`), 0644)
+
+	if _, err := ReadFile(path); err == nil {
+		t.Fatal("expected error for invalid config")
+	}
+}
+
+func TestLoad_HooksMergePerAction(t *testing.T) {
+	repoRoot := writeConfigs(t,
+		`{"hooks": {"post_create": "make setup", "timeout": "1m"}}`,
+		`{"hooks": {"post_create": "npm ci", "pre_remove": "docker compose down"}}`,
+	)
+
+	h := Load(repoRoot).Hooks
+	if h.PostCreate != "npm ci" || h.PreRemove != "docker compose down" || h.Timeout != "1m" {
+		t.Errorf("Hooks = %+v", h)
+	}
+}
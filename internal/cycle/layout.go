@@ -0,0 +1,96 @@
+package cycle
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sestinj/wt-cycle/internal/git"
+)
+
+// DefaultPathTemplate mirrors worktrunk's default layout: siblings of the
+// main worktree named <repo>.<branch>.
+const DefaultPathTemplate = "{parent}/{repo}.{branch}"
+
+// Layout maps numbered branches to worktree directories. The template may
+// use {parent} (the main worktree's parent directory), {repo} (the main
+// worktree's directory name) and {branch} (the branch name with "/"
+// replaced by "-"); {branch} must appear exactly once.
+type Layout struct {
+	Template string
+	Naming   *git.Naming
+}
+
+// ValidatePathTemplate checks that a path template is usable.
+func ValidatePathTemplate(template string) error {
+	if strings.Count(template, "{branch}") != 1 {
+		return fmt.Errorf("path template %q must contain {branch} exactly once", template)
+	}
+	return nil
+}
+
+// Path returns the worktree directory for branch, given the main worktree root.
+func (l Layout) Path(mainRoot, branch string) string {
+	return l.render(mainRoot, git.SanitizeBranch(branch))
+}
+
+// ExistingNums returns the numbers of directories on disk that match the
+// layout, whether or not git still knows about them.
+func (l Layout) ExistingNums(mainRoot string) []int {
+	var nums []int
+	for _, dir := range l.ExistingDirs(mainRoot) {
+		nums = append(nums, l.Num(mainRoot, dir))
+	}
+	return nums
+}
+
+// ExistingDirs returns the paths on disk that match the layout.
+func (l Layout) ExistingDirs(mainRoot string) []string {
+	prefix, suffix := l.split(mainRoot)
+	pattern := globEscape(prefix) + git.SanitizeBranch(l.Naming.Glob()) + globEscape(suffix)
+
+	matches, _ := filepath.Glob(pattern)
+	var dirs []string
+	for _, m := range matches {
+		if l.Num(mainRoot, m) >= 0 {
+			dirs = append(dirs, m)
+		}
+	}
+	return dirs
+}
+
+// Num is the inverse of Path: it returns the number of the branch whose
+// directory is path, or -1 if path does not fit the layout.
+func (l Layout) Num(mainRoot, path string) int {
+	prefix, suffix := l.split(mainRoot)
+	path = filepath.Clean(path)
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) || len(path) < len(prefix)+len(suffix) {
+		return -1
+	}
+	return l.Naming.DirNum(path[len(prefix) : len(path)-len(suffix)])
+}
+
+// split returns the rendered path around the {branch} placeholder.
+func (l Layout) split(mainRoot string) (prefix, suffix string) {
+	const marker = "\x00"
+	prefix, suffix, _ = strings.Cut(l.render(mainRoot, marker), marker)
+	return prefix, suffix
+}
+
+func (l Layout) render(mainRoot, branch string) string {
+	tmpl := l.Template
+	if tmpl == "" {
+		tmpl = DefaultPathTemplate
+	}
+	path := strings.NewReplacer(
+		"{parent}", filepath.Dir(mainRoot),
+		"{repo}", RepoBaseName(mainRoot, l.Naming),
+		"{branch}", branch,
+	).Replace(tmpl)
+	return filepath.Clean(path)
+}
+
+// globEscape escapes filepath.Match metacharacters in a literal path.
+func globEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(s)
+}
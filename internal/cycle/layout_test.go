@@ -0,0 +1,83 @@
+package cycle
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/git"
+)
+
+func TestLayout_Path(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		naming   string
+		branch   string
+		want     string
+	}{
+		{"default", "", "wt-{n}", "wt-3", "/src/myrepo.wt-3"},
+		{"nested", "{parent}/worktrees/{repo}/{branch}", "wt-{n}", "wt-3", "/src/worktrees/myrepo/wt-3"},
+		{"sanitized", "", "agent/x/{n}", "agent/x/3", "/src/myrepo.agent-x-3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := Layout{Template: tt.template, Naming: git.MustParseNaming(tt.naming)}
+			if got := l.Path("/src/myrepo", tt.branch); got != tt.want {
+				t.Errorf("Path = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayout_ExistingNums(t *testing.T) {
+	tmpDir := t.TempDir()
+	mainRoot := filepath.Join(tmpDir, "myrepo")
+	for _, d := range []string{
+		"myrepo",
+		"trees/myrepo/wt-2",
+		"trees/myrepo/wt-5",
+		"trees/myrepo/wt-x",
+		"trees/other/wt-9",
+		"myrepo.wt-7", // default layout, not ours
+	} {
+		os.MkdirAll(filepath.Join(tmpDir, d), 0755)
+	}
+
+	l := Layout{Template: "{parent}/trees/{repo}/{branch}", Naming: git.DefaultNaming()}
+	got := l.ExistingNums(mainRoot)
+	sort.Ints(got)
+	if len(got) != 2 || got[0] != 2 || got[1] != 5 {
+		t.Errorf("ExistingNums = %v, want [2 5]", got)
+	}
+}
+
+func TestValidatePathTemplate(t *testing.T) {
+	if err := ValidatePathTemplate("{parent}/{repo}.{branch}"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, bad := range []string{"{parent}/{repo}", "{branch}/{branch}"} {
+		if err := ValidatePathTemplate(bad); err == nil {
+			t.Errorf("ValidatePathTemplate(%q): expected error", bad)
+		}
+	}
+}
+
+func TestLayout_Num(t *testing.T) {
+	l := Layout{Template: "{parent}/trees/{repo}/{branch}", Naming: git.DefaultNaming()}
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/src/trees/myrepo/wt-4", 4},
+		{"/src/trees/myrepo/wt-x", -1},
+		{"/src/trees/other/wt-4", -1},
+		{"/src/myrepo.wt-4", -1},
+	}
+	for _, tt := range tests {
+		if got := l.Num("/src/myrepo", tt.path); got != tt.want {
+			t.Errorf("Num(%q) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+}
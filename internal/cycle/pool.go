@@ -0,0 +1,104 @@
+package cycle
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/procs"
+)
+
+// poolNaming names pool slots; the slot name fills {branch} in the path
+// template, e.g. "myrepo.pool-1". Slots are detached, so no branch exists.
+var poolNaming = git.MustParseNaming("pool-{n}")
+
+// PoolSlot is a pre-warmed worktree with a detached HEAD on the base ref,
+// waiting to be handed out by next.
+type PoolSlot struct {
+	Num  int
+	Path string
+}
+
+// PoolLayout returns the layout used for pool slot directories.
+func (d *Deps) PoolLayout() Layout {
+	return Layout{Template: d.PathTemplate, Naming: poolNaming}
+}
+
+// PoolSlotName returns the directory name component for slot k.
+func PoolSlotName(k int) string {
+	return poolNaming.Branch(k)
+}
+
+// PoolSlots returns the detached worktrees that live at pool slot paths,
+// ordered by slot number.
+func PoolSlots(d *Deps) ([]PoolSlot, error) {
+	mainRoot, err := MainWorktree(d)
+	if err != nil {
+		return nil, err
+	}
+	out, err := d.Git.WorktreeListPorcelain()
+	if err != nil {
+		return nil, fmt.Errorf("listing worktrees: %w", err)
+	}
+
+	layout := d.PoolLayout()
+	var slots []PoolSlot
+	for _, wt := range git.ParseWorktreeList(out) {
+		if wt.Branch != "" || wt.Bare {
+			continue
+		}
+		if n := layout.Num(mainRoot, wt.Path); n >= 0 {
+			slots = append(slots, PoolSlot{Num: n, Path: wt.Path})
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Num < slots[j].Num })
+	return slots, nil
+}
+
+// FreePoolSlot returns the first of slots that is safe to hand out: clean,
+// not leased and with no running process inside, the checks FindRecyclable
+// applies to recyclable worktrees. ok is false if there is none.
+func FreePoolSlot(d *Deps, slots []PoolSlot) (slot PoolSlot, ok bool) {
+	leased := make(map[string]string) // path -> holder
+	if d.Leases != nil {
+		leases, err := d.Leases.List()
+		if err != nil {
+			// Can't tell whether someone owns a slot; use none of them
+			d.Logf("warning: checking pool slot leases failed: %v", err)
+			return PoolSlot{}, false
+		}
+		for _, l := range leases {
+			leased[l.Path] = l.Describe()
+		}
+	}
+	var others []procs.Process
+	if d.Processes != nil {
+		var err error
+		if others, err = otherProcesses(d); err != nil {
+			d.Logf("warning: checking for processes in pool slots failed: %v", err)
+		}
+	}
+
+	for _, s := range slots {
+		if holder, ok := leased[s.Path]; ok {
+			if d.Verbose {
+				d.Logf("skip pool slot %s: leased by %s", s.Path, holder)
+			}
+			continue
+		}
+		if users := procs.Under(others, s.Path); len(users) > 0 {
+			if d.Verbose {
+				d.Logf("skip pool slot %s: in use by %s", s.Path, procs.Describe(users))
+			}
+			continue
+		}
+		if clean, err := d.Git.IsClean(s.Path); err != nil || !clean {
+			if d.Verbose {
+				d.Logf("skip pool slot %s: it has uncommitted changes", s.Path)
+			}
+			continue
+		}
+		return s, true
+	}
+	return PoolSlot{}, false
+}
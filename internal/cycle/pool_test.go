@@ -0,0 +1,69 @@
+package cycle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/lease"
+	"github.com/sestinj/wt-cycle/internal/procs"
+)
+
+func TestPoolSlots(t *testing.T) {
+	g := &mockGit{
+		repoRoot: "/src/myrepo",
+		wtPorcelain: "worktree /src/myrepo\nHEAD abc\nbranch refs/heads/main\n\n" +
+			"worktree /src/myrepo.pool-2\nHEAD abc\ndetached\n\n" +
+			"worktree /src/myrepo.wt-1\nHEAD abc\nbranch refs/heads/wt-1\n\n" +
+			"worktree /src/myrepo.pool-1\nHEAD abc\ndetached\n\n" +
+			"worktree /src/myrepo.pool-3\nHEAD abc\nbranch refs/heads/pool-3\n\n" +
+			"worktree /src/scratch\nHEAD abc\ndetached\n\n",
+	}
+	d := &Deps{Git: g, Logf: nopLogf}
+
+	slots, err := PoolSlots(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slots) != 2 {
+		t.Fatalf("expected 2 slots, got %+v", slots)
+	}
+	if slots[0].Num != 1 || slots[0].Path != "/src/myrepo.pool-1" {
+		t.Errorf("slots[0] = %+v", slots[0])
+	}
+	if slots[1].Num != 2 || slots[1].Path != "/src/myrepo.pool-2" {
+		t.Errorf("slots[1] = %+v", slots[1])
+	}
+}
+
+func TestFreePoolSlot(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	slots := []PoolSlot{
+		{Num: 1, Path: "/src/myrepo.pool-1"}, // dirty
+		{Num: 2, Path: "/src/myrepo.pool-2"}, // leased
+		{Num: 3, Path: "/src/myrepo.pool-3"}, // a process works in it
+		{Num: 4, Path: "/src/myrepo.pool-4"},
+	}
+	g := &mockGit{cleanPaths: map[string]bool{
+		slots[0].Path: false, slots[1].Path: true, slots[2].Path: true, slots[3].Path: true,
+	}}
+	leases := lease.New("/src/myrepo")
+	if err := leases.Claim(lease.Lease{Branch: "wt-9", Path: slots[1].Path, Owner: "agent-b", TTL: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	d := &Deps{
+		Git:    g,
+		Leases: leases,
+		Logf:   nopLogf,
+		Processes: func() ([]procs.Process, error) {
+			return []procs.Process{{PID: 4242, Command: "node", Cwd: slots[2].Path + "/web"}}, nil
+		},
+	}
+
+	slot, ok := FreePoolSlot(d, slots)
+	if !ok || slot != slots[3] {
+		t.Fatalf("FreePoolSlot = %+v, %v; want %+v", slot, ok, slots[3])
+	}
+	if _, ok := FreePoolSlot(d, slots[:3]); ok {
+		t.Error("expected no free slot")
+	}
+}
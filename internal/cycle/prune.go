@@ -0,0 +1,187 @@
+package cycle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sestinj/wt-cycle/internal/git"
+)
+
+// What prune does with directories that git has no worktree entry for.
+const (
+	OrphansKeep   = "keep"   // report them only (default)
+	OrphansRemove = "remove" // delete them from disk
+	OrphansAdopt  = "adopt"  // register them as worktrees again
+)
+
+// PrunePlan lists the worktree state that has drifted apart.
+type PrunePlan struct {
+	// Missing are worktree entries whose directory is gone.
+	Missing []git.Worktree
+	// Orphans are layout directories without a worktree entry.
+	Orphans []Orphan
+	// Branches are numbered branches left without a worktree once Missing
+	// is pruned, whose work is in the base ref or on the remote.
+	Branches []Recyclable
+	// Kept are dangling branches that are left alone because deleting them
+	// could lose work.
+	Kept []Skipped
+}
+
+// Empty reports whether there is nothing to prune.
+func (p *PrunePlan) Empty() bool {
+	return len(p.Missing) == 0 && len(p.Orphans) == 0 && len(p.Branches) == 0 && len(p.Kept) == 0
+}
+
+// Orphan is a directory at a worktree layout path that git has no entry for.
+type Orphan struct {
+	Path   string
+	Branch string // the branch its path is named after
+	// MovedFrom is set when the directory is a worktree that was moved by
+	// hand: it is the stale entry that `git worktree repair` reconnects.
+	MovedFrom *git.Worktree
+}
+
+// PlanPrune works out what prune would change. orphans is one of
+// OrphansKeep, OrphansRemove or OrphansAdopt; a kept or adopted directory
+// also keeps the entry it was moved from and the branch it is named after.
+func PlanPrune(d *Deps, orphans string) (*PrunePlan, error) {
+	out, err := d.Git.WorktreeListPorcelain()
+	if err != nil {
+		return nil, fmt.Errorf("listing worktrees: %w", err)
+	}
+	wts := git.ParseWorktreeList(out)
+	if len(wts) == 0 {
+		return nil, fmt.Errorf("no worktrees listed")
+	}
+	mainRoot := wts[0].Path
+
+	plan := &PrunePlan{}
+	known := make(map[string]bool)
+	missing := make(map[string]git.Worktree)
+	for i, wt := range wts {
+		known[filepath.Clean(wt.Path)] = true
+		if i == 0 || wt.Bare {
+			continue
+		}
+		if _, err := os.Stat(wt.Path); os.IsNotExist(err) {
+			missing[filepath.Clean(wt.Path)] = wt
+		}
+	}
+
+	// Branches that must survive: those with a worktree that stays, and
+	// those an orphan is about to be given back.
+	keep := make(map[string]bool)
+	names := d.Names()
+	for _, dir := range d.Layout().ExistingDirs(mainRoot) {
+		if known[filepath.Clean(dir)] || isRepository(dir) {
+			continue
+		}
+		o := Orphan{Path: dir, Branch: names.Branch(d.Layout().Num(mainRoot, dir))}
+		if old := movedFrom(dir); old != "" {
+			if wt, ok := missing[old]; ok {
+				o.MovedFrom = &wt
+				if orphans != OrphansRemove {
+					delete(missing, old)
+				}
+			}
+		}
+		if orphans != OrphansRemove {
+			keep[o.Branch] = true
+		}
+		plan.Orphans = append(plan.Orphans, o)
+	}
+
+	for _, wt := range wts {
+		if _, gone := missing[filepath.Clean(wt.Path)]; gone {
+			plan.Missing = append(plan.Missing, wt)
+		} else if wt.Branch != "" {
+			keep[wt.Branch] = true
+		}
+	}
+
+	refs, err := d.Git.ForEachRef("refs/heads/" + names.Glob())
+	if err != nil {
+		return nil, fmt.Errorf("listing branches: %w", err)
+	}
+	current, _ := d.Git.CurrentBranch()
+	var facts *branchFacts
+	for _, b := range names.Filter(refs) {
+		if keep[b] || b == current {
+			continue
+		}
+		if d.Leases != nil {
+			if l, err := d.Leases.Active(b); err != nil || l != nil {
+				continue
+			}
+		}
+		if facts == nil {
+			if facts, err = gatherBranchFacts(d); err != nil {
+				return nil, err
+			}
+		}
+		if s := danglingKept(d, facts, b); s != nil {
+			plan.Kept = append(plan.Kept, *s)
+			continue
+		}
+		plan.Branches = append(plan.Branches, Recyclable{
+			Branch:  b,
+			PRState: facts.prStates[b],
+			Archive: d.ClosedPRPolicy == PolicyArchive && facts.closedUnmerged(b),
+		})
+	}
+	return plan, nil
+}
+
+// danglingKept applies FindRecyclable's rules to a branch without a
+// worktree: it may be deleted if git finds it merged, or if the remote or
+// a PR head has all its commits and ClosedPRPolicy allows it. Otherwise it
+// returns why the branch is kept.
+func danglingKept(d *Deps, f *branchFacts, branch string) *Skipped {
+	kept := &Skipped{Branch: branch, PRState: f.prStates[branch]}
+	if f.closedUnmerged(branch) && d.ClosedPRPolicy == PolicyKeep {
+		kept.Reason = "closed-unmerged"
+		return kept
+	}
+	if _, ok := f.gitMerged[branch]; ok {
+		return nil
+	}
+	n, err := d.Git.UnpushedCommits(branch, f.prHeads[branch]...)
+	switch {
+	case err != nil:
+		kept.Reason, kept.Detail = "check-failed", err.Error()
+	case n > 0:
+		kept.Reason, kept.Detail = "unpushed", fmt.Sprintf("%d commit(s) only in this repo", n)
+	default:
+		return nil
+	}
+	return kept
+}
+
+// isRepository reports whether dir holds a repository of its own, as
+// opposed to a worktree whose .git is a file pointing into another repo.
+func isRepository(dir string) bool {
+	fi, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil && fi.IsDir()
+}
+
+// movedFrom returns the path a worktree directory was registered at, read
+// from the admin directory its .git file points to, or "" if dir is not a
+// linked worktree.
+func movedFrom(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, ".git"))
+	if err != nil {
+		return ""
+	}
+	admin, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return ""
+	}
+	data, err = os.ReadFile(filepath.Join(admin, "gitdir"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Dir(filepath.Clean(strings.TrimSpace(string(data))))
+}
@@ -0,0 +1,185 @@
+package cycle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/forge"
+)
+
+// pruneFixture lays out a repo whose worktree state has drifted:
+//
+//	myrepo.wt-1  registered and present
+//	myrepo.wt-2  registered, directory deleted
+//	myrepo.wt-4  directory with no entry
+//	myrepo.wt-6  myrepo.wt-5 moved by hand
+//
+// and the branches wt-1 to wt-5 and wt-7.
+func pruneFixture(t *testing.T) (*Deps, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	repoRoot := filepath.Join(tmpDir, "myrepo")
+	wt := func(n int) string { return filepath.Join(tmpDir, fmt.Sprintf("myrepo.wt-%d", n)) }
+
+	for _, dir := range []string{repoRoot, wt(1), wt(4), wt(6)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	admin := filepath.Join(repoRoot, ".git", "worktrees", "myrepo.wt-5")
+	os.MkdirAll(admin, 0755)
+	os.WriteFile(filepath.Join(admin, "gitdir"), []byte(filepath.Join(wt(5), ".git")+"\n"), 0644)
+	os.WriteFile(filepath.Join(wt(6), ".git"), []byte("gitdir: "+admin+"\n"), 0644)
+
+	g := &mockGit{
+		currentBranch: "main",
+		repoRoot:      repoRoot,
+		wtPorcelain: fmt.Sprintf(`worktree %s
+HEAD abc
+branch refs/heads/main
+
+worktree %s
+HEAD abc
+branch refs/heads/wt-1
+
+worktree %s
+HEAD abc
+branch refs/heads/wt-2
+prunable gitdir file points to non-existent location
+
+worktree %s
+HEAD abc
+branch refs/heads/wt-5
+prunable gitdir file points to non-existent location
+
+`, repoRoot, wt(1), wt(2), wt(5)),
+		refs: []string{"main", "wt-1", "wt-2", "wt-3", "wt-4", "wt-5", "wt-7"},
+	}
+	return &Deps{Git: g, Forge: &mockGH{}, Logf: nopLogf}, tmpDir
+}
+
+func branchNames(rs []Recyclable) []string {
+	var names []string
+	for _, r := range rs {
+		names = append(names, r.Branch)
+	}
+	return names
+}
+
+func TestPlanPrune_Keep(t *testing.T) {
+	d, tmpDir := pruneFixture(t)
+
+	plan, err := PlanPrune(d, OrphansKeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Missing) != 1 || plan.Missing[0].Branch != "wt-2" {
+		t.Errorf("Missing = %+v, want only wt-2", plan.Missing)
+	}
+	if len(plan.Orphans) != 2 {
+		t.Fatalf("Orphans = %+v, want 2", plan.Orphans)
+	}
+	if o := plan.Orphans[0]; o.Path != filepath.Join(tmpDir, "myrepo.wt-4") || o.Branch != "wt-4" || o.MovedFrom != nil {
+		t.Errorf("Orphans[0] = %+v", o)
+	}
+	if o := plan.Orphans[1]; o.MovedFrom == nil || o.MovedFrom.Branch != "wt-5" {
+		t.Errorf("Orphans[1] = %+v, want moved from wt-5", o)
+	}
+	// wt-4 and wt-5 belong to the kept directories.
+	if want := []string{"wt-2", "wt-3", "wt-7"}; !reflect.DeepEqual(branchNames(plan.Branches), want) {
+		t.Errorf("Branches = %v, want %v", plan.Branches, want)
+	}
+}
+
+func TestPlanPrune_Remove(t *testing.T) {
+	d, _ := pruneFixture(t)
+
+	plan, err := PlanPrune(d, OrphansRemove)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Missing) != 2 {
+		t.Errorf("Missing = %+v, want wt-2 and wt-5", plan.Missing)
+	}
+	if len(plan.Orphans) != 2 {
+		t.Errorf("Orphans = %+v, want 2", plan.Orphans)
+	}
+	if want := []string{"wt-2", "wt-3", "wt-4", "wt-5", "wt-7"}; !reflect.DeepEqual(branchNames(plan.Branches), want) {
+		t.Errorf("Branches = %v, want %v", plan.Branches, want)
+	}
+}
+
+func TestPlanPrune_SkipsRepositoriesAndCurrent(t *testing.T) {
+	d, tmpDir := pruneFixture(t)
+	d.Git.(*mockGit).currentBranch = "wt-7"
+	// A clone that happens to sit at a layout path is not ours to touch.
+	os.MkdirAll(filepath.Join(tmpDir, "myrepo.wt-4", ".git"), 0755)
+
+	plan, err := PlanPrune(d, OrphansRemove)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Orphans) != 1 || plan.Orphans[0].Branch != "wt-6" {
+		t.Errorf("Orphans = %+v, want only wt-6", plan.Orphans)
+	}
+	for _, b := range plan.Branches {
+		if b.Branch == "wt-7" {
+			t.Errorf("current branch wt-7 planned for deletion: %v", plan.Branches)
+		}
+	}
+}
+
+// TestPlanPrune_KeepsUnlandedWork checks that dangling branches are only
+// deleted when their work is merged or on the remote.
+func TestPlanPrune_KeepsUnlandedWork(t *testing.T) {
+	d, _ := pruneFixture(t)
+	g := d.Git.(*mockGit)
+	// wt-2: unpushed commits but merged into the base ref
+	// wt-3: unpushed commits, no PR
+	// wt-7: PR closed without merging
+	g.merged = []string{"wt-2"}
+	g.unpushed = map[string]int{"wt-2": 1, "wt-3": 2}
+	d.Forge = &mockGH{prs: []forge.PR{{Branch: "wt-7", State: forge.StateClosed, HeadSHA: "bbb"}}}
+
+	d.ClosedPRPolicy = PolicyKeep
+	plan, err := PlanPrune(d, OrphansKeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"wt-2"}; !reflect.DeepEqual(branchNames(plan.Branches), want) {
+		t.Errorf("Branches = %v, want %v", branchNames(plan.Branches), want)
+	}
+	if len(plan.Kept) != 2 || plan.Kept[0].Branch != "wt-3" || plan.Kept[0].Reason != "unpushed" ||
+		plan.Kept[1].Branch != "wt-7" || plan.Kept[1].Reason != "closed-unmerged" {
+		t.Errorf("Kept = %+v, want wt-3 unpushed and wt-7 closed-unmerged", plan.Kept)
+	}
+
+	d.ClosedPRPolicy = PolicyArchive
+	plan, err = PlanPrune(d, OrphansKeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Branches) != 2 || plan.Branches[1].Branch != "wt-7" || !plan.Branches[1].Archive {
+		t.Errorf("Branches = %+v, want wt-7 pinned", plan.Branches)
+	}
+}
+
+func TestPlanPrune_Clean(t *testing.T) {
+	dir := t.TempDir()
+	g := &mockGit{
+		wtPorcelain: fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/main\n\n", dir),
+		refs:        []string{"main"},
+	}
+	plan, err := PlanPrune(&Deps{Git: g, Logf: nopLogf}, OrphansKeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Errorf("expected an empty plan, got %+v", plan)
+	}
+}
@@ -1,29 +1,45 @@
 package cycle
 
 import (
+	"crypto/md5"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/sestinj/wt-cycle/internal/cache"
+	"github.com/sestinj/wt-cycle/internal/forge"
 	"github.com/sestinj/wt-cycle/internal/git"
-	"github.com/sestinj/wt-cycle/internal/github"
+	"github.com/sestinj/wt-cycle/internal/lease"
+	"github.com/sestinj/wt-cycle/internal/procs"
+)
+
+// Policies for branches whose PR was closed without being merged.
+const (
+	PolicyRecycle = "recycle" // treat like a merged PR (default)
+	PolicyArchive = "archive" // archive the branch tip, then recycle
+	PolicyKeep    = "keep"    // never recycle; skipped as "closed-unmerged"
 )
 
 // Recyclable represents a worktree branch that can be safely recycled.
 type Recyclable struct {
-	Branch string
-	Path   string
+	Branch  string
+	Path    string
+	PRState string // forge.StateMerged, forge.StateClosed, or "" if no PR is known
+	// Archive asks the caller to archive the branch tip before deleting it.
+	Archive bool
 }
 
 // Skipped represents a candidate that was not recyclable.
 type Skipped struct {
-	Branch string
-	Path   string
-	Reason string // "current", "no-worktree", "missing-dir", "dirty", "check-failed"
+	Branch  string
+	Path    string
+	Reason  string // "current", "no-worktree", "missing-dir", "dirty", "check-failed", "leased", "in-use", "closed-unmerged", "unpushed", "has-stash"
+	Detail  string // extra context for the reason, e.g. the PIDs holding an in-use worktree
+	PRState string
 }
 
 // FindResult holds both recyclable and skipped candidates.
@@ -34,22 +50,77 @@ type FindResult struct {
 
 // Deps bundles the dependencies for the cycle logic.
 type Deps struct {
-	Git    git.Client
-	GitHub github.Client
-	Cache  *cache.Cache
+	Git   git.Client
+	Forge forge.Provider // closed pull/merge request lookup
+	Cache *cache.Cache
+
+	// Remote and BaseBranch identify the branch that worktrees are cut
+	// from and merged into. Empty values fall back to origin and main.
+	Remote     string
+	BaseBranch string
+	// Naming is the branch naming scheme. Nil means the default wt-{n}.
+	Naming *git.Naming
+	// PathTemplate places worktree directories. Empty means DefaultPathTemplate.
+	PathTemplate string
+	// Leases records worktrees claimed by agents. Nil disables the check.
+	Leases *lease.Store
+	// Processes lists running processes for the in-use check. Nil disables it.
+	Processes func() ([]procs.Process, error)
+	// ClosedPRPolicy handles branches whose PR was closed without merging:
+	// PolicyRecycle, PolicyArchive or PolicyKeep. Empty means PolicyRecycle.
+	ClosedPRPolicy string
 
 	NoCache bool
 	Verbose bool
 	Logf    func(format string, args ...interface{}) // writes to stderr
 }
 
+// RemoteName returns the configured remote, defaulting to "origin".
+func (d *Deps) RemoteName() string {
+	if d.Remote == "" {
+		return "origin"
+	}
+	return d.Remote
+}
+
+// BranchName returns the configured base branch, defaulting to "main".
+func (d *Deps) BranchName() string {
+	if d.BaseBranch == "" {
+		return "main"
+	}
+	return d.BaseBranch
+}
+
+// Names returns the branch naming scheme, defaulting to wt-{n}.
+func (d *Deps) Names() *git.Naming {
+	if d.Naming == nil {
+		return git.DefaultNaming()
+	}
+	return d.Naming
+}
+
+// Layout returns the worktree directory layout.
+func (d *Deps) Layout() Layout {
+	return Layout{Template: d.PathTemplate, Naming: d.Names()}
+}
+
+// BaseRef returns the remote-tracking ref worktrees are based on, e.g. "origin/main".
+func (d *Deps) BaseRef() string {
+	return d.RemoteName() + "/" + d.BranchName()
+}
+
 // FindRecyclable returns worktree branches that are safe to recycle.
 // A branch is recyclable if:
-// 1. It matches wt-N pattern
-// 2. It's merged into origin/main OR its PR is closed/merged
+// 1. It matches the naming scheme (wt-N by default)
+// 2. It's merged into the base ref (directly, squashed or rebased) OR its PR is
+//    merged, or closed unmerged and ClosedPRPolicy allows it
 // 3. Its worktree directory exists
 // 4. Its worktree is clean (no uncommitted changes)
 // 5. It's not the current branch
+// 6. A PR-detected branch has no local commits missing from the remote and the PR head
+// 7. No stash entry was made on it
+// 8. It has no live lease (when Leases is set)
+// 9. No running process has its cwd inside it (when Processes is set)
 func FindRecyclable(d *Deps) (*FindResult, error) {
 	// Get current branch to exclude
 	currentBranch, err := d.Git.CurrentBranch()
@@ -57,38 +128,20 @@ func FindRecyclable(d *Deps) (*FindResult, error) {
 		return nil, fmt.Errorf("getting current branch: %w", err)
 	}
 
-	// Fire-and-forget fetch — use stale origin/main for this invocation.
+	// Fire-and-forget fetch — use the stale base ref for this invocation.
 	// The data is at most a few minutes old; next call will see the update.
 	go func() {
-		if err := d.Git.FetchOriginMain(); err != nil && d.Verbose {
+		if err := d.Git.FetchBase(d.RemoteName(), d.BranchName()); err != nil && d.Verbose {
 			d.Logf("warning: background git fetch failed: %v", err)
 		}
 	}()
 
-	// GitHub lookup (cached)
-	var closedBranches []string
-	var ghErr error
-	closedBranches, ghErr = cachedClosedBranches(d)
-
-	// Get merged branches (after fetch)
-	merged, err := d.Git.MergedBranches("wt-*")
+	f, err := gatherBranchFacts(d)
 	if err != nil {
-		return nil, fmt.Errorf("listing merged branches: %w", err)
-	}
-	mergedBranches := git.FilterWtBranches(merged)
-
-	// Union merged + closed PR branches
-	candidateSet := make(map[string]struct{})
-	for _, b := range mergedBranches {
-		candidateSet[b] = struct{}{}
-	}
-	if ghErr != nil {
-		d.Logf("warning: GitHub API failed: %v", ghErr)
-	} else {
-		for _, b := range git.FilterWtBranches(closedBranches) {
-			candidateSet[b] = struct{}{}
-		}
+		return nil, err
 	}
+	candidateSet, gitMerged, prStates := f.candidates, f.gitMerged, f.prStates
+	closedUnmerged := f.closedUnmerged
 
 	if len(candidateSet) == 0 {
 		return &FindResult{}, nil
@@ -110,6 +163,14 @@ func FindRecyclable(d *Deps) (*FindResult, error) {
 	var toCheck []candidate
 	var skipped []Skipped
 	for branch := range candidateSet {
+		if closedUnmerged(branch) && d.ClosedPRPolicy == PolicyKeep {
+			if d.Verbose {
+				d.Logf("skip %s: PR closed without merging", branch)
+			}
+			skipped = append(skipped, Skipped{Branch: branch, Path: byBranch[branch].Path, Reason: "closed-unmerged"})
+			continue
+		}
+
 		if branch == currentBranch {
 			if d.Verbose {
 				d.Logf("skip %s: current branch", branch)
@@ -178,76 +239,479 @@ func FindRecyclable(d *Deps) (*FindResult, error) {
 		recyclable = append(recyclable, Recyclable{Branch: r.branch, Path: r.path})
 	}
 
+	// Git-merged branches are fully contained in the base ref; anything
+	// found only through the forge may have local commits it never saw.
+	var prOnly []Recyclable
+	var viaGit []Recyclable
+	for _, r := range recyclable {
+		if _, ok := gitMerged[r.Branch]; ok {
+			viaGit = append(viaGit, r)
+		} else {
+			prOnly = append(prOnly, r)
+		}
+	}
+	prOnly, skipped = filterUnpushed(d, prOnly, skipped, f.prHeads)
+	recyclable = append(viaGit, prOnly...)
+
+	recyclable, skipped = filterStashed(d, recyclable, skipped)
+	recyclable, skipped = filterLeased(d, recyclable, skipped)
+	recyclable, skipped = filterInUse(d, recyclable, skipped)
+
+	for i := range recyclable {
+		recyclable[i].PRState = prStates[recyclable[i].Branch]
+		recyclable[i].Archive = d.ClosedPRPolicy == PolicyArchive && closedUnmerged(recyclable[i].Branch)
+	}
+	for i := range skipped {
+		skipped[i].PRState = prStates[skipped[i].Branch]
+	}
+
 	return &FindResult{Recyclable: recyclable, Skipped: skipped}, nil
 }
 
-// CollectExistingNums gathers all existing wt-N numbers from refs and worktree directories.
+// branchFacts is what is known about numbered branches before looking at
+// their worktrees.
+type branchFacts struct {
+	candidates map[string]struct{} // merged, squashed or with a closed PR
+	landed     map[string]struct{} // work known to be in the base ref
+	gitMerged  map[string]struct{} // ...according to git itself
+	prStates   map[string]string   // branch -> forge.StateMerged or StateClosed
+	prHeads    map[string][]string // branch -> PR head commits
+}
+
+// closedUnmerged reports whether branch's PR was closed without merging;
+// it may be abandoned work someone wants back.
+func (f *branchFacts) closedUnmerged(branch string) bool {
+	_, ok := f.landed[branch]
+	return !ok && f.prStates[branch] == forge.StateClosed
+}
+
+// gatherBranchFacts finds the numbered branches that are merged into the
+// base ref or have a merged or closed PR.
+func gatherBranchFacts(d *Deps) (*branchFacts, error) {
+	// Forge lookup (cached)
+	closedPRs, prErr := cachedClosedPRs(d)
+
+	// Get merged branches (after fetch)
+	names := d.Names()
+	merged, err := d.Git.MergedBranches(d.BaseRef(), names.Glob())
+	if err != nil {
+		return nil, fmt.Errorf("listing merged branches: %w", err)
+	}
+	mergedBranches := names.Filter(merged)
+
+	// Union merged + closed PR branches
+	candidateSet := make(map[string]struct{})
+	landed := make(map[string]struct{})    // work known to be in the base ref
+	gitMerged := make(map[string]struct{}) // ...according to git itself
+	for _, b := range mergedBranches {
+		candidateSet[b] = struct{}{}
+		landed[b] = struct{}{}
+		gitMerged[b] = struct{}{}
+	}
+	prStates := make(map[string]string)
+	prHeads := make(map[string][]string)
+	if prErr != nil {
+		d.Logf("warning: PR lookup failed: %v", prErr)
+	} else {
+		for _, pr := range currentPRs(d, closedPRs) {
+			candidateSet[pr.Branch] = struct{}{}
+			// A branch can have several PRs that it still contains, e.g.
+			// one merged and a follow-up closed; if any was merged, treat
+			// the branch as merged.
+			if prStates[pr.Branch] != forge.StateMerged {
+				prStates[pr.Branch] = pr.State
+			}
+			if pr.State == forge.StateMerged {
+				landed[pr.Branch] = struct{}{}
+			}
+			if pr.HeadSHA != "" {
+				prHeads[pr.Branch] = append(prHeads[pr.Branch], pr.HeadSHA)
+			}
+		}
+	}
+
+	// Squash/rebase merges are invisible to --merged; detect them offline
+	// so recycling doesn't depend on the PR lookup succeeding.
+	for _, b := range squashMergedBranches(d, landed) {
+		candidateSet[b] = struct{}{}
+		landed[b] = struct{}{}
+		gitMerged[b] = struct{}{}
+	}
+
+	return &branchFacts{
+		candidates: candidateSet,
+		landed:     landed,
+		gitMerged:  gitMerged,
+		prStates:   prStates,
+		prHeads:    prHeads,
+	}, nil
+}
+
+// currentPRs keeps the PRs for numbered branches whose head commit the
+// local branch still contains. Branch names get reused, so a PR whose head
+// is not on the branch came from an earlier branch of the same name and
+// says nothing about this one.
+func currentPRs(d *Deps, prs []forge.PR) []forge.PR {
+	names := d.Names()
+	contains := make([]bool, len(prs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 16) // bound concurrency
+	for i, pr := range prs {
+		if names.Num(pr.Branch) < 0 || pr.HeadSHA == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, pr forge.PR) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// An error usually means the head was never fetched, so it
+			// is not on the branch either.
+			contains[i], _ = d.Git.IsAncestor(pr.HeadSHA, "refs/heads/"+pr.Branch)
+		}(i, pr)
+	}
+	wg.Wait()
+
+	var kept []forge.PR
+	for i, pr := range prs {
+		if !contains[i] {
+			if d.Verbose && names.Num(pr.Branch) >= 0 {
+				d.Logf("ignoring a PR for %s: its head %s is not on the branch", pr.Branch, pr.HeadSHA)
+			}
+			continue
+		}
+		kept = append(kept, pr)
+	}
+	return kept
+}
+
+// filterUnpushed moves branches with commits that exist only locally from
+// recyclable to skipped, since deleting the branch would lose them.
+func filterUnpushed(d *Deps, recyclable []Recyclable, skipped []Skipped, prHeads map[string][]string) ([]Recyclable, []Skipped) {
+	counts := make([]int, len(recyclable))
+	errs := make([]error, len(recyclable))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 16) // bound concurrency
+	for i, r := range recyclable {
+		wg.Add(1)
+		go func(i int, r Recyclable) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			counts[i], errs[i] = d.Git.UnpushedCommits(r.Branch, prHeads[r.Branch]...)
+		}(i, r)
+	}
+	wg.Wait()
+
+	var kept []Recyclable
+	for i, r := range recyclable {
+		if errs[i] != nil {
+			if d.Verbose {
+				d.Logf("skip %s: unpushed check failed: %v", r.Branch, errs[i])
+			}
+			skipped = append(skipped, Skipped{Branch: r.Branch, Path: r.Path, Reason: "check-failed"})
+			continue
+		}
+		if counts[i] > 0 {
+			detail := fmt.Sprintf("%d commit(s)", counts[i])
+			if d.Verbose {
+				d.Logf("skip %s: %s not pushed", r.Branch, detail)
+			}
+			skipped = append(skipped, Skipped{Branch: r.Branch, Path: r.Path, Reason: "unpushed", Detail: detail})
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept, skipped
+}
+
+// filterStashed moves branches that have stash entries from recyclable to
+// skipped. Stashes are not part of the branch, so deleting it orphans them.
+func filterStashed(d *Deps, recyclable []Recyclable, skipped []Skipped) ([]Recyclable, []Skipped) {
+	if len(recyclable) == 0 {
+		return recyclable, skipped
+	}
+	stashes, err := BranchStashes(d)
+	if err != nil {
+		// Can't tell which branches have stashes; keep them all
+		if d.Verbose {
+			d.Logf("warning: listing stashes failed: %v", err)
+		}
+		for _, r := range recyclable {
+			skipped = append(skipped, Skipped{Branch: r.Branch, Path: r.Path, Reason: "check-failed"})
+		}
+		return nil, skipped
+	}
+
+	var kept []Recyclable
+	for _, r := range recyclable {
+		if n := len(stashes[r.Branch]); n > 0 {
+			detail := fmt.Sprintf("%d stash(es)", n)
+			if d.Verbose {
+				d.Logf("skip %s: %s", r.Branch, detail)
+			}
+			skipped = append(skipped, Skipped{Branch: r.Branch, Path: r.Path, Reason: "has-stash", Detail: detail})
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept, skipped
+}
+
+// BranchStashes groups the repo's stash entries by the branch they were
+// made on. Branch names get reused, so a stash only counts for the branch
+// of its name if the commit it was made on is still on that branch.
+func BranchStashes(d *Deps) (map[string][]git.Stash, error) {
+	out, err := d.Git.StashList()
+	if err != nil {
+		return nil, err
+	}
+	byBranch := make(map[string][]git.Stash)
+	for _, s := range git.ParseStashList(out) {
+		if s.Branch == "" {
+			continue
+		}
+		// If in doubt, e.g. because the check failed, keep the stash with
+		// the branch so that it holds the branch back.
+		if on, err := d.Git.IsAncestor(s.Base, "refs/heads/"+s.Branch); err == nil && !on {
+			if d.Verbose {
+				d.Logf("ignoring %s: made on an earlier %s", s.Ref, s.Branch)
+			}
+			continue
+		}
+		byBranch[s.Branch] = append(byBranch[s.Branch], s)
+	}
+	return byBranch, nil
+}
+
+// filterLeased moves worktrees with a live lease from recyclable to skipped.
+func filterLeased(d *Deps, recyclable []Recyclable, skipped []Skipped) ([]Recyclable, []Skipped) {
+	if d.Leases == nil {
+		return recyclable, skipped
+	}
+	var kept []Recyclable
+	for _, r := range recyclable {
+		l, err := d.Leases.Active(r.Branch)
+		if err != nil {
+			// Can't tell whether someone owns it; err on the side of keeping it
+			if d.Verbose {
+				d.Logf("skip %s: lease check failed: %v", r.Branch, err)
+			}
+			skipped = append(skipped, Skipped{Branch: r.Branch, Path: r.Path, Reason: "check-failed"})
+			continue
+		}
+		if l == nil {
+			kept = append(kept, r)
+			continue
+		}
+		if d.Verbose {
+			d.Logf("skip %s: leased by %s", r.Branch, l.Describe())
+		}
+		skipped = append(skipped, Skipped{Branch: r.Branch, Path: r.Path, Reason: "leased", Detail: l.Describe()})
+	}
+	return kept, skipped
+}
+
+// filterInUse moves worktrees that a running process has its cwd (or open
+// files) in from recyclable to skipped.
+func filterInUse(d *Deps, recyclable []Recyclable, skipped []Skipped) ([]Recyclable, []Skipped) {
+	if d.Processes == nil || len(recyclable) == 0 {
+		return recyclable, skipped
+	}
+	others, err := otherProcesses(d)
+	if err != nil {
+		d.Logf("warning: checking for processes in worktrees failed: %v", err)
+		return recyclable, skipped
+	}
+
+	var kept []Recyclable
+	for _, r := range recyclable {
+		users := procs.Under(others, r.Path)
+		if len(users) == 0 {
+			kept = append(kept, r)
+			continue
+		}
+		detail := procs.Describe(users)
+		if d.Verbose {
+			d.Logf("skip %s: in use by %s", r.Branch, detail)
+		}
+		skipped = append(skipped, Skipped{Branch: r.Branch, Path: r.Path, Reason: "in-use", Detail: detail})
+	}
+	return kept, skipped
+}
+
+// otherProcesses lists the running processes other than this one.
+func otherProcesses(d *Deps) ([]procs.Process, error) {
+	all, err := d.Processes()
+	if err != nil {
+		return nil, err
+	}
+	self := os.Getpid()
+	var others []procs.Process
+	for _, p := range all {
+		if p.PID != self {
+			others = append(others, p)
+		}
+	}
+	return others, nil
+}
+
+// squashMergedBranches returns local numbered branches, not already in known,
+// whose changes landed in the base ref via a squash or rebase merge.
+func squashMergedBranches(d *Deps, known map[string]struct{}) []string {
+	names := d.Names()
+	refs, err := d.Git.ForEachRef("refs/heads/" + names.Glob())
+	if err != nil {
+		if d.Verbose {
+			d.Logf("warning: listing branches for squash detection failed: %v", err)
+		}
+		return nil
+	}
+
+	var toCheck []string
+	for _, b := range names.Filter(refs) {
+		if _, ok := known[b]; !ok {
+			toCheck = append(toCheck, b)
+		}
+	}
+
+	merged := make([]bool, len(toCheck))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 16) // bound concurrency
+	for i, b := range toCheck {
+		wg.Add(1)
+		go func(i int, b string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			ok, err := d.Git.SquashMerged(d.BaseRef(), b)
+			if err != nil {
+				if d.Verbose {
+					d.Logf("warning: squash detection failed for %s: %v", b, err)
+				}
+				return
+			}
+			merged[i] = ok
+		}(i, b)
+	}
+	wg.Wait()
+
+	var result []string
+	for i, b := range toCheck {
+		if merged[i] {
+			if d.Verbose {
+				d.Logf("%s: squash/rebase merged into %s", b, d.BaseRef())
+			}
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// CollectExistingNums gathers all existing branch numbers from refs and worktree directories.
 func CollectExistingNums(d *Deps) ([]int, error) {
-	repoRoot, err := d.Git.RepoRoot()
+	mainRoot, err := MainWorktree(d)
 	if err != nil {
 		return nil, err
 	}
 
-	refs, err := d.Git.ForEachRef("refs/heads/wt-*", "refs/remotes/origin/wt-*")
+	names := d.Names()
+	remote := d.RemoteName()
+	refs, err := d.Git.ForEachRef("refs/heads/"+names.Glob(), "refs/remotes/"+remote+"/"+names.Glob())
 	if err != nil {
 		return nil, err
 	}
 
 	var nums []int
 	for _, ref := range refs {
-		ref = strings.TrimPrefix(ref, "origin/")
-		if n := git.ExtractWtNum(ref); n >= 0 {
+		ref = strings.TrimPrefix(ref, remote+"/")
+		if n := names.Num(ref); n >= 0 {
 			nums = append(nums, n)
 		}
 	}
 
-	// Scan sibling directories
-	repoParent := filepath.Dir(repoRoot)
-	baseName := filepath.Base(repoRoot)
-	// Strip .wt-N suffix from base name to get the root repo name
-	if idx := strings.Index(baseName, ".wt-"); idx != -1 {
-		baseName = baseName[:idx]
-	}
+	// Scan directories laid out by the path template
+	nums = append(nums, d.Layout().ExistingNums(mainRoot)...)
 
-	entries, _ := os.ReadDir(repoParent)
-	for _, e := range entries {
-		name := e.Name()
-		prefix := baseName + ".wt-"
-		if strings.HasPrefix(name, prefix) {
-			rest := strings.TrimPrefix(name, prefix)
-			if n := git.ExtractWtNum("wt-" + rest); n >= 0 {
-				nums = append(nums, n)
-			}
+	return nums, nil
+}
+
+// MainWorktree returns the main worktree's root, which anchors the path
+// template. It is the first entry of `git worktree list`; if that is
+// unavailable, the current repo root is used.
+func MainWorktree(d *Deps) (string, error) {
+	if out, err := d.Git.WorktreeListPorcelain(); err == nil {
+		if wts := git.ParseWorktreeList(out); len(wts) > 0 {
+			return wts[0].Path, nil
 		}
 	}
+	return d.Git.RepoRoot()
+}
 
-	return nums, nil
+// WorktreePath looks up the directory of the worktree checked out on
+// branch, as reported by `git worktree list`.
+func WorktreePath(d *Deps, branch string) (string, error) {
+	out, err := d.Git.WorktreeListPorcelain()
+	if err != nil {
+		return "", fmt.Errorf("listing worktrees: %w", err)
+	}
+	wt, ok := git.WorktreesByBranch(git.ParseWorktreeList(out))[branch]
+	if !ok {
+		return "", fmt.Errorf("no worktree found for branch %s", branch)
+	}
+	return wt.Path, nil
 }
 
-func cachedClosedBranches(d *Deps) ([]string, error) {
+// RepoBaseName returns the repository directory name with any worktree
+// suffix stripped, so "myrepo.wt-5" and "myrepo" both yield "myrepo".
+func RepoBaseName(repoRoot string, names *git.Naming) string {
+	baseName := filepath.Base(repoRoot)
+	if idx := strings.Index(baseName, "."+names.DirPrefix()); idx != -1 {
+		baseName = baseName[:idx]
+	}
+	return baseName
+}
+
+// cachedClosedPRs returns the forge's closed pull requests. Providers
+// that can look up branches one by one are asked only about local
+// numbered branches.
+func cachedClosedPRs(d *Deps) ([]forge.PR, error) {
 	cacheKey := "pr-states"
+	fetch := d.Forge.ClosedPRs
+	if bp, ok := d.Forge.(forge.BranchProvider); ok {
+		branches, err := d.Git.ForEachRef("refs/heads/" + d.Names().Glob())
+		if err != nil {
+			return nil, fmt.Errorf("listing branches: %w", err)
+		}
+		if len(branches) == 0 {
+			return nil, nil
+		}
+		sort.Strings(branches)
+		cacheKey = fmt.Sprintf("pr-states-%x", md5.Sum([]byte(strings.Join(branches, "\n"))))
+		fetch = func() ([]forge.PR, error) { return bp.ClosedPRsFor(branches) }
+	}
 
 	if !d.NoCache && d.Cache != nil {
 		if data := d.Cache.Get(cacheKey); data != nil {
-			var branches []string
-			if err := json.Unmarshal(data, &branches); err == nil {
+			var prs []forge.PR
+			if err := json.Unmarshal(data, &prs); err == nil {
 				if d.Verbose {
-					d.Logf("using cached PR data (%d branches)", len(branches))
+					d.Logf("using cached PR data (%d PRs)", len(prs))
 				}
-				return branches, nil
+				return prs, nil
 			}
 		}
 	}
 
-	branches, err := d.GitHub.ClosedPRBranches()
+	prs, err := fetch()
 	if err != nil {
 		return nil, err
 	}
 
 	if d.Cache != nil {
-		data, _ := json.Marshal(branches)
+		data, _ := json.Marshal(prs)
 		d.Cache.Set(cacheKey, data)
 	}
 
-	return branches, nil
+	return prs, nil
 }
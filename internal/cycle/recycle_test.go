@@ -3,7 +3,13 @@ package cycle
 import (
 	"fmt"
 	"os"
+	"sync"
 	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/forge"
+	"github.com/sestinj/wt-cycle/internal/git"
+	"github.com/sestinj/wt-cycle/internal/lease"
+	"github.com/sestinj/wt-cycle/internal/procs"
 )
 
 // mockGit implements git.Client for testing.
@@ -13,16 +19,45 @@ type mockGit struct {
 	wtPorcelain   string
 	refs          []string
 	cleanPaths    map[string]bool // path -> isClean
-	repoRoot      string
+	squashed      map[string]bool // branch -> squash/rebase merged
+	unpushed      map[string]int  // branch -> local-only commits
+	stashList     string          // raw `git stash list` output
+	diverged      map[string]bool // PR heads that are not on their branch
+
+	mu           sync.Mutex
+	unpushedTips map[string][]string // branch -> extra tips passed to UnpushedCommits
+	repoRoot     string
 }
 
-func (m *mockGit) FetchOriginMain() error                     { return nil }
-func (m *mockGit) MergedBranches(_ string) ([]string, error)  { return m.merged, nil }
-func (m *mockGit) WorktreeListPorcelain() (string, error)     { return m.wtPorcelain, nil }
-func (m *mockGit) ForEachRef(_ ...string) ([]string, error)   { return m.refs, nil }
-func (m *mockGit) CurrentBranch() (string, error)             { return m.currentBranch, nil }
-func (m *mockGit) RepoRoot() (string, error)                  { return m.repoRoot, nil }
-func (m *mockGit) Run(_ ...string) (string, error)            { return "", nil }
+func (m *mockGit) FetchBase(_, _ string) error                  { return nil }
+func (m *mockGit) DefaultBranch(_ string) (string, error)       { return "main", nil }
+func (m *mockGit) RemoteURL(_ string) (string, error)           { return "", nil }
+func (m *mockGit) MergedBranches(_, _ string) ([]string, error) { return m.merged, nil }
+func (m *mockGit) SquashMerged(_, b string) (bool, error)       { return m.squashed[b], nil }
+func (m *mockGit) WorktreeListPorcelain() (string, error)       { return m.wtPorcelain, nil }
+func (m *mockGit) ForEachRef(_ ...string) ([]string, error)     { return m.refs, nil }
+func (m *mockGit) ListRefs(_ ...string) ([]git.Ref, error)      { return nil, nil }
+func (m *mockGit) IsAncestor(commit, _ string) (bool, error) {
+	return !m.diverged[commit], nil
+}
+func (m *mockGit) ResolveRef(ref string) (string, error) {
+	return "", fmt.Errorf("unknown ref: %s", ref)
+}
+func (m *mockGit) UnpushedCommits(branch string, extraTips ...string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.unpushedTips == nil {
+		m.unpushedTips = make(map[string][]string)
+	}
+	m.unpushedTips[branch] = extraTips
+	return m.unpushed[branch], nil
+}
+func (m *mockGit) StashList() (string, error)      { return m.stashList, nil }
+func (m *mockGit) UpdateRef(_, _ string) error     { return nil }
+func (m *mockGit) DeleteRef(_ string) error        { return nil }
+func (m *mockGit) CurrentBranch() (string, error)  { return m.currentBranch, nil }
+func (m *mockGit) RepoRoot() (string, error)       { return m.repoRoot, nil }
+func (m *mockGit) Run(_ ...string) (string, error) { return "", nil }
 func (m *mockGit) IsClean(path string) (bool, error) {
 	clean, ok := m.cleanPaths[path]
 	if !ok {
@@ -31,13 +66,20 @@ func (m *mockGit) IsClean(path string) (bool, error) {
 	return clean, nil
 }
 
-// mockGH implements github.Client for testing.
+// mockGH implements forge.Provider for testing.
 type mockGH struct {
-	branches []string
+	branches []string   // reported as merged PRs
+	prs      []forge.PR // reported as-is, after branches
 	err      error
 }
 
-func (m *mockGH) ClosedPRBranches() ([]string, error) { return m.branches, m.err }
+func (m *mockGH) ClosedPRs() ([]forge.PR, error) {
+	var prs []forge.PR
+	for _, b := range m.branches {
+		prs = append(prs, forge.PR{Branch: b, State: forge.StateMerged, HeadSHA: "head-" + b})
+	}
+	return append(prs, m.prs...), m.err
+}
 
 func nopLogf(string, ...interface{}) {}
 
@@ -66,7 +108,7 @@ branch refs/heads/wt-3
 
 	gh := &mockGH{branches: []string{"wt-2", "wt-3"}}
 
-	d := &Deps{Git: g, GitHub: gh, Logf: nopLogf}
+	d := &Deps{Git: g, Forge: gh, Logf: nopLogf}
 	result, err := FindRecyclable(d)
 	if err != nil {
 		t.Fatal(err)
@@ -105,7 +147,7 @@ branch refs/heads/wt-2
 		cleanPaths: map[string]bool{dirClean: true, dirDirty: false},
 	}
 
-	d := &Deps{Git: g, GitHub: &mockGH{}, Logf: nopLogf}
+	d := &Deps{Git: g, Forge: &mockGH{}, Logf: nopLogf}
 	result, err := FindRecyclable(d)
 	if err != nil {
 		t.Fatal(err)
@@ -130,7 +172,7 @@ branch refs/heads/wt-1
 		cleanPaths: map[string]bool{dir: true},
 	}
 
-	d := &Deps{Git: g, GitHub: &mockGH{}, Logf: nopLogf}
+	d := &Deps{Git: g, Forge: &mockGH{}, Logf: nopLogf}
 	result, err := FindRecyclable(d)
 	if err != nil {
 		t.Fatal(err)
@@ -153,7 +195,7 @@ branch refs/heads/wt-1
 		cleanPaths: map[string]bool{},
 	}
 
-	d := &Deps{Git: g, GitHub: &mockGH{}, Logf: nopLogf}
+	d := &Deps{Git: g, Forge: &mockGH{}, Logf: nopLogf}
 	result, err := FindRecyclable(d)
 	if err != nil {
 		t.Fatal(err)
@@ -186,7 +228,7 @@ branch refs/heads/wt-2
 	// wt-2 was squash-merged (PR closed), not in git merged list
 	gh := &mockGH{branches: []string{"wt-2"}}
 
-	d := &Deps{Git: g, GitHub: gh, Logf: nopLogf}
+	d := &Deps{Git: g, Forge: gh, Logf: nopLogf}
 	result, err := FindRecyclable(d)
 	if err != nil {
 		t.Fatal(err)
@@ -197,6 +239,171 @@ branch refs/heads/wt-2
 	}
 }
 
+// branchGH is a forge.BranchProvider that only answers per-branch lookups.
+type branchGH struct {
+	mockGH
+	asked []string
+}
+
+func (m *branchGH) ClosedPRs() ([]forge.PR, error) {
+	return nil, fmt.Errorf("ClosedPRs called on a branch provider")
+}
+
+func (m *branchGH) ClosedPRsFor(branches []string) ([]forge.PR, error) {
+	m.asked = branches
+	return m.mockGH.ClosedPRs()
+}
+
+func TestFindRecyclable_BranchProvider(t *testing.T) {
+	dir := t.TempDir()
+	g := &mockGit{
+		currentBranch: "main",
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD abc\nbranch refs/heads/wt-2\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		refs:          []string{"wt-2", "wt-1"},
+	}
+	gh := &branchGH{mockGH: mockGH{branches: []string{"wt-2"}}}
+
+	result, err := FindRecyclable(&Deps{Git: g, Forge: gh, Logf: nopLogf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(gh.asked) != "[wt-1 wt-2]" {
+		t.Errorf("asked about %v, want the local numbered branches", gh.asked)
+	}
+	if len(result.Recyclable) != 1 || result.Recyclable[0].Branch != "wt-2" {
+		t.Errorf("recyclable = %+v, want wt-2", result.Recyclable)
+	}
+}
+
+func TestFindRecyclable_SquashMergedOffline(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{}, // --merged never sees squash merges
+		refs:          []string{"wt-1", "wt-2"},
+		squashed:      map[string]bool{"wt-1": true},
+		wtPorcelain: fmt.Sprintf(`worktree %s
+HEAD abc
+branch refs/heads/wt-1
+
+worktree %s
+HEAD def
+branch refs/heads/wt-2
+
+`, dir1, dir2),
+		cleanPaths: map[string]bool{dir1: true, dir2: true},
+	}
+
+	// GitHub is unavailable
+	gh := &mockGH{err: fmt.Errorf("gh: not logged in")}
+
+	d := &Deps{Git: g, Forge: gh, Logf: nopLogf}
+	result, err := FindRecyclable(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Recyclable) != 1 || result.Recyclable[0].Branch != "wt-1" {
+		t.Fatalf("expected only wt-1 (squash merged), got %+v", result.Recyclable)
+	}
+}
+
+func TestFindRecyclable_SkipsInUse(t *testing.T) {
+	dirFree := t.TempDir()
+	dirBusy := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1", "wt-2"},
+		wtPorcelain: fmt.Sprintf(`worktree %s
+HEAD abc
+branch refs/heads/wt-1
+
+worktree %s
+HEAD def
+branch refs/heads/wt-2
+
+`, dirFree, dirBusy),
+		cleanPaths: map[string]bool{dirFree: true, dirBusy: true},
+	}
+
+	d := &Deps{
+		Git:   g,
+		Forge: &mockGH{},
+		Logf:  nopLogf,
+		Processes: func() ([]procs.Process, error) {
+			return []procs.Process{
+				{PID: 4242, Command: "node", Cwd: dirBusy + "/web"},
+				{PID: os.Getpid(), Command: "wt-cycle", Cwd: dirFree}, // ourselves
+			}, nil
+		},
+	}
+	result, err := FindRecyclable(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Recyclable) != 1 || result.Recyclable[0].Branch != "wt-1" {
+		t.Fatalf("expected only wt-1, got %+v", result.Recyclable)
+	}
+	var found bool
+	for _, s := range result.Skipped {
+		if s.Branch == "wt-2" {
+			found = true
+			if s.Reason != "in-use" || s.Detail != "4242 node" {
+				t.Errorf("wt-2 skipped = %+v, want in-use by 4242 node", s)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected wt-2 in skipped, got %+v", result.Skipped)
+	}
+}
+
+func TestFindRecyclable_SkipsLeased(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1", "wt-2"},
+		wtPorcelain: fmt.Sprintf(`worktree %s
+HEAD abc
+branch refs/heads/wt-1
+
+worktree %s
+HEAD def
+branch refs/heads/wt-2
+
+`, dir1, dir2),
+		cleanPaths: map[string]bool{dir1: true, dir2: true},
+	}
+
+	leases := lease.New(dir1)
+	if err := leases.Claim(lease.Lease{Branch: "wt-2", Path: dir2, Owner: "agent-b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Deps{Git: g, Forge: &mockGH{}, Leases: leases, Logf: nopLogf}
+	result, err := FindRecyclable(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Recyclable) != 1 || result.Recyclable[0].Branch != "wt-1" {
+		t.Fatalf("expected only wt-1, got %+v", result.Recyclable)
+	}
+	for _, s := range result.Skipped {
+		if s.Branch == "wt-2" && (s.Reason != "leased" || s.Detail != "agent-b") {
+			t.Errorf("wt-2 skipped = %+v, want leased by agent-b", s)
+		}
+	}
+}
+
 func TestCollectExistingNums(t *testing.T) {
 	// Create a fake directory structure
 	tmpDir := t.TempDir()
@@ -236,3 +443,233 @@ func TestCollectExistingNums(t *testing.T) {
 		t.Error("should not include 99 (different repo)")
 	}
 }
+
+func TestCollectExistingNums_CustomNaming(t *testing.T) {
+	t.Setenv("USER", "nate")
+	tmpDir := t.TempDir()
+	repoDir := tmpDir + "/myrepo"
+	os.MkdirAll(repoDir, 0755)
+	os.MkdirAll(tmpDir+"/myrepo.agent-nate-2", 0755)
+	os.MkdirAll(tmpDir+"/myrepo.wt-9", 0755) // old scheme, ignored
+
+	g := &mockGit{
+		repoRoot: repoDir,
+		refs:     []string{"agent/nate/1", "origin/agent/nate/4", "wt-8"},
+	}
+
+	d := &Deps{Git: g, Naming: git.MustParseNaming("agent/{user}/{n}"), Logf: nopLogf}
+	nums, err := CollectExistingNums(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	numSet := map[int]bool{}
+	for _, n := range nums {
+		numSet[n] = true
+	}
+	for _, expected := range []int{1, 2, 4} {
+		if !numSet[expected] {
+			t.Errorf("expected %d in nums, got %v", expected, nums)
+		}
+	}
+	if numSet[8] || numSet[9] {
+		t.Errorf("should not include wt-N numbers, got %v", nums)
+	}
+}
+
+func TestFindRecyclable_ClosedPRPolicy(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+
+	newDeps := func(policy string) *Deps {
+		g := &mockGit{
+			currentBranch: "main",
+			wtPorcelain: fmt.Sprintf(
+				"worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\nworktree %s\nHEAD def\nbranch refs/heads/wt-2\n\n",
+				dir1, dir2,
+			),
+			cleanPaths: map[string]bool{dir1: true, dir2: true},
+		}
+		gh := &mockGH{prs: []forge.PR{
+			{Branch: "wt-1", State: forge.StateMerged, HeadSHA: "aaa"},
+			{Branch: "wt-2", State: forge.StateClosed, HeadSHA: "bbb"},
+			{Branch: "wt-1", State: forge.StateClosed, HeadSHA: "ccc"}, // follow-up PR
+		}}
+		return &Deps{Git: g, Forge: gh, ClosedPRPolicy: policy, Logf: nopLogf}
+	}
+
+	t.Run("recycle", func(t *testing.T) {
+		result, err := FindRecyclable(newDeps(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Recyclable) != 2 {
+			t.Fatalf("expected 2 recyclable, got %+v", result.Recyclable)
+		}
+		for _, r := range result.Recyclable {
+			want := map[string]string{"wt-1": forge.StateMerged, "wt-2": forge.StateClosed}[r.Branch]
+			if r.PRState != want {
+				t.Errorf("%s PRState = %q, want %q", r.Branch, r.PRState, want)
+			}
+			if r.Archive {
+				t.Errorf("%s should not be archived under the recycle policy", r.Branch)
+			}
+		}
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		result, err := FindRecyclable(newDeps(PolicyArchive))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Recyclable) != 2 {
+			t.Fatalf("expected 2 recyclable, got %+v", result.Recyclable)
+		}
+		for _, r := range result.Recyclable {
+			if r.Archive != (r.Branch == "wt-2") {
+				t.Errorf("%s Archive = %v", r.Branch, r.Archive)
+			}
+		}
+	})
+
+	t.Run("keep", func(t *testing.T) {
+		result, err := FindRecyclable(newDeps(PolicyKeep))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Recyclable) != 1 || result.Recyclable[0].Branch != "wt-1" {
+			t.Fatalf("expected only wt-1 recyclable, got %+v", result.Recyclable)
+		}
+		if len(result.Skipped) != 1 {
+			t.Fatalf("expected 1 skipped, got %+v", result.Skipped)
+		}
+		s := result.Skipped[0]
+		if s.Branch != "wt-2" || s.Reason != "closed-unmerged" || s.PRState != forge.StateClosed || s.Path != dir2 {
+			t.Errorf("skipped = %+v", s)
+		}
+	})
+}
+
+func TestFindRecyclable_SkipsUnpushed(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1"},
+		wtPorcelain: fmt.Sprintf(
+			"worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\nworktree %s\nHEAD def\nbranch refs/heads/wt-2\n\n",
+			dir1, dir2,
+		),
+		cleanPaths: map[string]bool{dir1: true, dir2: true},
+		unpushed:   map[string]int{"wt-2": 3},
+	}
+	gh := &mockGH{prs: []forge.PR{{Branch: "wt-2", State: forge.StateMerged, HeadSHA: "cafe"}}}
+
+	d := &Deps{Git: g, Forge: gh, Logf: nopLogf}
+	result, err := FindRecyclable(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Recyclable) != 1 || result.Recyclable[0].Branch != "wt-1" {
+		t.Fatalf("expected only wt-1 recyclable, got %+v", result.Recyclable)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Reason != "unpushed" || result.Skipped[0].Detail != "3 commit(s)" {
+		t.Fatalf("expected wt-2 skipped as unpushed, got %+v", result.Skipped)
+	}
+	if tips := g.unpushedTips["wt-2"]; len(tips) != 1 || tips[0] != "cafe" {
+		t.Errorf("PR head not passed to UnpushedCommits: %v", tips)
+	}
+	if _, checked := g.unpushedTips["wt-1"]; checked {
+		t.Error("git-merged wt-1 should not need an unpushed check")
+	}
+}
+
+// TestFindRecyclable_IgnoresPRsOfReusedNames checks that a merged PR from
+// an earlier branch of the same name, whose head the current branch does
+// not contain, does not make the branch recyclable.
+func TestFindRecyclable_IgnoresPRsOfReusedNames(t *testing.T) {
+	dir := t.TempDir()
+	g := &mockGit{
+		currentBranch: "main",
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD def\nbranch refs/heads/wt-3\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		diverged:      map[string]bool{"old": true},
+	}
+	gh := &mockGH{prs: []forge.PR{{Branch: "wt-3", State: forge.StateMerged, HeadSHA: "old"}}}
+
+	d := &Deps{Git: g, Forge: gh, Logf: nopLogf}
+	result, err := FindRecyclable(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Recyclable) != 0 {
+		t.Fatalf("expected nothing recyclable, got %+v", result.Recyclable)
+	}
+	if _, checked := g.unpushedTips["wt-3"]; checked {
+		t.Error("wt-3 should not be a candidate at all")
+	}
+}
+
+func TestFindRecyclable_SkipsStashed(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-1", "wt-2"},
+		wtPorcelain: fmt.Sprintf(
+			"worktree %s\nHEAD abc\nbranch refs/heads/wt-1\n\nworktree %s\nHEAD def\nbranch refs/heads/wt-2\n\n",
+			dir1, dir2,
+		),
+		cleanPaths: map[string]bool{dir1: true, dir2: true},
+		stashList: "stash@{0}\taaa\taaa-base aaa-index\tWIP on wt-2: abc wip\n" +
+			"stash@{1}\tbbb\tbbb-base bbb-index\tOn wt-2: experiment\n" +
+			"stash@{2}\tccc\tccc-base ccc-index\tOn main: unrelated\n",
+	}
+
+	d := &Deps{Git: g, Forge: &mockGH{}, Logf: nopLogf}
+	result, err := FindRecyclable(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Recyclable) != 1 || result.Recyclable[0].Branch != "wt-1" {
+		t.Fatalf("expected only wt-1 recyclable, got %+v", result.Recyclable)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Reason != "has-stash" || result.Skipped[0].Detail != "2 stash(es)" {
+		t.Fatalf("expected wt-2 skipped with 2 stashes, got %+v", result.Skipped)
+	}
+}
+
+// TestFindRecyclable_IgnoresStashesOfReusedNames checks that a stash made
+// on an earlier branch of the same name does not hold the current one back.
+func TestFindRecyclable_IgnoresStashesOfReusedNames(t *testing.T) {
+	dir := t.TempDir()
+	g := &mockGit{
+		currentBranch: "main",
+		merged:        []string{"wt-3"},
+		wtPorcelain:   fmt.Sprintf("worktree %s\nHEAD def\nbranch refs/heads/wt-3\n\n", dir),
+		cleanPaths:    map[string]bool{dir: true},
+		stashList:     "stash@{0}\taaa\told-tip aaa-index\tWIP on wt-3: 1234567 old work\n",
+		diverged:      map[string]bool{"old-tip": true},
+	}
+
+	d := &Deps{Git: g, Forge: &mockGH{}, Logf: nopLogf}
+	result, err := FindRecyclable(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Recyclable) != 1 || result.Recyclable[0].Branch != "wt-3" {
+		t.Fatalf("expected wt-3 recyclable, got %+v (skipped %+v)", result.Recyclable, result.Skipped)
+	}
+
+	stashes, err := BranchStashes(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stashes["wt-3"]) != 0 {
+		t.Errorf("old stash attributed to the current wt-3: %+v", stashes)
+	}
+}
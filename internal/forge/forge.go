@@ -0,0 +1,59 @@
+package forge
+
+import (
+	"strings"
+)
+
+// Provider names accepted in the forge.provider config setting.
+const (
+	ProviderGH     = "gh"     // GitHub via the gh CLI
+	ProviderGitHub = "github" // GitHub REST API
+	ProviderGitLab = "gitlab"
+	ProviderGitea  = "gitea" // also Forgejo and Codeberg
+)
+
+// Pull request states reported by providers.
+const (
+	StateMerged = "MERGED"
+	StateClosed = "CLOSED" // closed without merging
+)
+
+// PR is a pull or merge request that is no longer open.
+type PR struct {
+	Branch  string `json:"branch"`
+	State   string `json:"state"`              // StateMerged or StateClosed
+	HeadSHA string `json:"head_sha,omitempty"` // last commit the forge saw on the branch
+}
+
+// Provider reports pull or merge requests that are no longer open on the
+// code forge.
+type Provider interface {
+	// ClosedPRs returns every merged or closed pull request in the repo.
+	ClosedPRs() ([]PR, error)
+}
+
+// BranchProvider is a Provider that can also look up just the pull
+// requests from the given source branches, which on a large repo is far
+// cheaper than listing every closed one.
+type BranchProvider interface {
+	Provider
+	// ClosedPRsFor returns the merged or closed pull requests whose source
+	// branch is one of branches.
+	ClosedPRsFor(branches []string) ([]PR, error)
+}
+
+// Detect guesses the forge from a remote host name. It returns "" when the
+// host is not recognized.
+func Detect(host string) string {
+	host = strings.ToLower(host)
+	switch {
+	case host == "github.com" || strings.Contains(host, "github"):
+		return ProviderGitHub
+	case strings.Contains(host, "gitlab"):
+		return ProviderGitLab
+	case host == "codeberg.org" || strings.Contains(host, "gitea") || strings.Contains(host, "forgejo"):
+		return ProviderGitea
+	default:
+		return ""
+	}
+}
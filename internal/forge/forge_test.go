@@ -0,0 +1,87 @@
+package forge
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		url, host, path string
+	}{
+		{"git@github.com:acme/widgets.git", "github.com", "acme/widgets"},
+		{"https://github.com/acme/widgets", "github.com", "acme/widgets"},
+		{"https://user:pw@gitlab.example.com/group/sub/widgets.git", "gitlab.example.com", "group/sub/widgets"},
+		{"ssh://git@codeberg.org:2222/acme/widgets.git", "codeberg.org", "acme/widgets"},
+	}
+	for _, tt := range tests {
+		host, path, err := ParseRemoteURL(tt.url)
+		if err != nil {
+			t.Errorf("ParseRemoteURL(%q): %v", tt.url, err)
+			continue
+		}
+		if host != tt.host || path != tt.path {
+			t.Errorf("ParseRemoteURL(%q) = %q, %q; want %q, %q", tt.url, host, path, tt.host, tt.path)
+		}
+	}
+
+	for _, bad := range []string{"", "/local/path/repo", "widgets"} {
+		if _, _, err := ParseRemoteURL(bad); err == nil {
+			t.Errorf("ParseRemoteURL(%q): expected error", bad)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := map[string]string{
+		"github.com":         ProviderGitHub,
+		"github.example.com": ProviderGitHub,
+		"gitlab.com":         ProviderGitLab,
+		"GitLab.corp.net":    ProviderGitLab,
+		"codeberg.org":       ProviderGitea,
+		"forgejo.corp.net":   ProviderGitea,
+		"git.example.com":    "",
+	}
+	for host, want := range tests {
+		if got := Detect(host); got != want {
+			t.Errorf("Detect(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestGetPage_XNextPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "tok" {
+			t.Errorf("missing token header")
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("X-Next-Page", "2")
+		}
+		fmt.Fprint(w, `[1, 2]`)
+	}))
+	defer srv.Close()
+
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", "tok")
+	var page []int
+	next, err := GetPage(nil, srv.URL+"/items?state=closed", "test API", header, &page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 {
+		t.Errorf("page = %v", page)
+	}
+	if want := srv.URL + "/items?page=2&state=closed"; next != want {
+		t.Errorf("next = %q, want %q", next, want)
+	}
+
+	next, err = GetPage(nil, next, "test API", header, &page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != "" {
+		t.Errorf("expected last page, got next = %q", next)
+	}
+}
@@ -0,0 +1,64 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// GetPage fetches one page of a paginated JSON API into v and returns the
+// URL of the next page, or "" on the last page. name prefixes errors.
+func GetPage(client *http.Client, url, name string, header http.Header, v interface{}) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, vals := range header {
+		req.Header[k] = vals
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("%s: %s: %s", name, resp.Status, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("parsing %s response: %w", name, err)
+	}
+
+	if next := NextLink(resp.Header.Get("Link")); next != "" {
+		return next, nil
+	}
+	// GitLab omits the Link header for large collections but still
+	// reports the next page number.
+	if page := resp.Header.Get("X-Next-Page"); page != "" {
+		if _, err := strconv.Atoi(page); err == nil {
+			q := req.URL.Query()
+			q.Set("page", page)
+			req.URL.RawQuery = q.Encode()
+			return req.URL.String(), nil
+		}
+	}
+	return "", nil
+}
+
+var nextLinkRe = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="next"`)
+
+// NextLink extracts the rel="next" URL from an RFC 8288 Link header.
+func NextLink(header string) string {
+	if m := nextLinkRe.FindStringSubmatch(header); m != nil {
+		return m[1]
+	}
+	return ""
+}
@@ -0,0 +1,32 @@
+package forge
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseRemoteURL splits a git remote URL into its host and repository path
+// (e.g. "github.com" and "owner/repo"). It understands https://, ssh://
+// and scp-like git@host:path URLs; a trailing .git is dropped.
+func ParseRemoteURL(remote string) (host, path string, err error) {
+	remote = strings.TrimSpace(remote)
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return "", "", fmt.Errorf("parsing remote URL %q: %w", remote, err)
+		}
+		host, path = u.Hostname(), u.Path
+	} else if at, rest, ok := strings.Cut(remote, ":"); ok && !strings.HasPrefix(rest, "/") {
+		// scp-like syntax: [user@]host:path
+		if i := strings.LastIndex(at, "@"); i >= 0 {
+			at = at[i+1:]
+		}
+		host, path = at, rest
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || path == "" {
+		return "", "", fmt.Errorf("unrecognized remote URL %q", remote)
+	}
+	return host, path, nil
+}
@@ -3,19 +3,44 @@ package git
 import (
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
 // Client abstracts git operations for testability.
 type Client interface {
-	// FetchOriginMain runs git fetch -q origin main.
-	FetchOriginMain() error
-	// MergedBranches returns branches matching pattern merged into origin/main.
-	MergedBranches(pattern string) ([]string, error)
+	// FetchBase runs git fetch -q <remote> <branch>.
+	FetchBase(remote, branch string) error
+	// DefaultBranch returns the branch that refs/remotes/<remote>/HEAD points at.
+	DefaultBranch(remote string) (string, error)
+	// RemoteURL returns the fetch URL configured for remote.
+	RemoteURL(remote string) (string, error)
+	// MergedBranches returns branches matching pattern merged into base (e.g. "origin/main").
+	MergedBranches(base, pattern string) ([]string, error)
+	// SquashMerged reports whether branch's changes already landed in base
+	// through a squash or rebase merge, which --merged cannot see.
+	SquashMerged(base, branch string) (bool, error)
 	// WorktreeListPorcelain returns raw `git worktree list --porcelain` output.
 	WorktreeListPorcelain() (string, error)
+	// StashList returns raw `git stash list` output in StashListFormat.
+	StashList() (string, error)
 	// ForEachRef returns ref short names matching the given patterns.
 	ForEachRef(patterns ...string) ([]string, error)
+	// ListRefs returns the full names and targets of refs under the given
+	// prefixes, e.g. "refs/wt-cycle/".
+	ListRefs(prefixes ...string) ([]Ref, error)
+	// UnpushedCommits counts commits on branch that are reachable from
+	// neither any remote-tracking ref nor the given extra tips (e.g. a
+	// PR's head commit). Tips that don't exist locally are ignored.
+	UnpushedCommits(branch string, extraTips ...string) (int, error)
+	// IsAncestor reports whether commit is reachable from ref (or is it).
+	IsAncestor(commit, ref string) (bool, error)
+	// ResolveRef returns the commit SHA that ref points at.
+	ResolveRef(ref string) (string, error)
+	// UpdateRef points ref at sha, creating it if needed.
+	UpdateRef(ref, sha string) error
+	// DeleteRef removes ref.
+	DeleteRef(ref string) error
 	// IsClean returns true if the worktree at path has no modifications or untracked files.
 	IsClean(path string) (bool, error)
 	// CurrentBranch returns the current branch name, or "" if detached.
@@ -33,24 +58,82 @@ func NewExecClient() *ExecClient {
 	return &ExecClient{}
 }
 
-func (c *ExecClient) FetchOriginMain() error {
-	cmd := exec.Command("git", "fetch", "-q", "origin", "main")
+func (c *ExecClient) FetchBase(remote, branch string) error {
+	cmd := exec.Command("git", "fetch", "-q", remote, branch)
 	cmd.Stderr = nil
 	return cmd.Run()
 }
 
-func (c *ExecClient) MergedBranches(pattern string) ([]string, error) {
-	out, err := c.Run("branch", "--merged", "origin/main", "--list", pattern, "--format=%(refname:short)")
+func (c *ExecClient) DefaultBranch(remote string) (string, error) {
+	out, err := c.Run("symbolic-ref", "--short", "refs/remotes/"+remote+"/HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(out, remote+"/"), nil
+}
+
+func (c *ExecClient) RemoteURL(remote string) (string, error) {
+	return c.Run("remote", "get-url", remote)
+}
+
+func (c *ExecClient) MergedBranches(base, pattern string) ([]string, error) {
+	out, err := c.Run("branch", "--merged", base, "--list", pattern, "--format=%(refname:short)")
 	if err != nil {
 		return nil, err
 	}
 	return nonEmpty(strings.Split(out, "\n")), nil
 }
 
+func (c *ExecClient) SquashMerged(base, branch string) (bool, error) {
+	// Rebase merge: every commit on branch has a patch-equivalent in base.
+	out, err := c.Run("cherry", base, branch)
+	if err != nil {
+		return false, err
+	}
+	if allCherryPicked(out) {
+		return true, nil
+	}
+
+	// Squash merge: collapse the branch into a single throwaway commit on
+	// top of the merge base and check whether base has an equivalent patch.
+	mergeBase, err := c.Run("merge-base", base, branch)
+	if err != nil {
+		return false, err
+	}
+	tree, err := c.Run("rev-parse", branch+"^{tree}")
+	if err != nil {
+		return false, err
+	}
+	probe, err := c.Run("commit-tree", tree, "-p", mergeBase, "-m", "wt-cycle squash probe")
+	if err != nil {
+		return false, err
+	}
+	out, err = c.Run("cherry", base, probe)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(out, "-"), nil
+}
+
+// allCherryPicked reports whether every line of `git cherry` output is
+// marked "-" (already upstream). Empty output means nothing is unmerged.
+func allCherryPicked(out string) bool {
+	for _, line := range nonEmpty(strings.Split(out, "\n")) {
+		if !strings.HasPrefix(line, "-") {
+			return false
+		}
+	}
+	return true
+}
+
 func (c *ExecClient) WorktreeListPorcelain() (string, error) {
 	return c.Run("worktree", "list", "--porcelain")
 }
 
+func (c *ExecClient) StashList() (string, error) {
+	return c.Run("stash", "list", "--format="+StashListFormat)
+}
+
 func (c *ExecClient) ForEachRef(patterns ...string) ([]string, error) {
 	args := append([]string{"for-each-ref", "--format=%(refname:short)"}, patterns...)
 	out, err := c.Run(args...)
@@ -60,6 +143,54 @@ func (c *ExecClient) ForEachRef(patterns ...string) ([]string, error) {
 	return nonEmpty(strings.Split(out, "\n")), nil
 }
 
+func (c *ExecClient) ListRefs(prefixes ...string) ([]Ref, error) {
+	args := append([]string{"for-each-ref", "--format=" + RefListFormat}, prefixes...)
+	out, err := c.Run(args...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRefList(out), nil
+}
+
+func (c *ExecClient) UnpushedCommits(branch string, extraTips ...string) (int, error) {
+	args := []string{"rev-list", "--count", "refs/heads/" + branch, "--not", "--remotes"}
+	for _, tip := range extraTips {
+		if _, err := c.ResolveRef(tip); err == nil {
+			args = append(args, tip)
+		}
+	}
+	out, err := c.Run(args...)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(out)
+}
+
+func (c *ExecClient) IsAncestor(commit, ref string) (bool, error) {
+	cmd := exec.Command("git", "merge-base", "--is-ancestor", commit, ref)
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return false, nil
+		}
+		return false, fmt.Errorf("git merge-base --is-ancestor %s %s: %w", commit, ref, err)
+	}
+	return true, nil
+}
+
+func (c *ExecClient) ResolveRef(ref string) (string, error) {
+	return c.Run("rev-parse", "--verify", "-q", ref+"^{commit}")
+}
+
+func (c *ExecClient) UpdateRef(ref, sha string) error {
+	_, err := c.Run("update-ref", ref, sha)
+	return err
+}
+
+func (c *ExecClient) DeleteRef(ref string) error {
+	_, err := c.Run("update-ref", "-d", ref)
+	return err
+}
+
 func (c *ExecClient) IsClean(path string) (bool, error) {
 	// Check for staged and unstaged changes
 	cmd := exec.Command("git", "-C", path, "status", "--porcelain")
@@ -0,0 +1,152 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRepo is a throwaway repository used to exercise ExecClient against
+// real git. The process cwd is switched into it for the test's duration.
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	for _, kv := range [][2]string{
+		{"GIT_AUTHOR_NAME", "test"}, {"GIT_AUTHOR_EMAIL", "test@example.com"},
+		{"GIT_COMMITTER_NAME", "test"}, {"GIT_COMMITTER_EMAIL", "test@example.com"},
+	} {
+		t.Setenv(kv[0], kv[1])
+	}
+	r := &testRepo{t: t, dir: t.TempDir()}
+	r.git("init", "-q", "-b", "main")
+	r.commit("a.txt", "initial")
+	t.Chdir(r.dir)
+	return r
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", r.dir}, args...)...).CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+// commit writes name (with its own name as content) and commits it.
+func (r *testRepo) commit(name, msg string) {
+	r.t.Helper()
+	if err := os.WriteFile(filepath.Join(r.dir, name), []byte(name+"\n"), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+	r.git("add", name)
+	r.git("commit", "-q", "-m", msg)
+}
+
+func TestSquashMerged(t *testing.T) {
+	r := newTestRepo(t)
+	c := NewExecClient()
+
+	// wt-1: two commits, squash-merged into main
+	r.git("checkout", "-q", "-b", "wt-1")
+	r.commit("b.txt", "b")
+	r.commit("c.txt", "c")
+	r.git("checkout", "-q", "main")
+	r.git("merge", "-q", "--squash", "wt-1")
+	r.git("commit", "-q", "-m", "squashed wt-1")
+
+	// wt-2: rebase-merged (cherry-picked) into main
+	r.git("checkout", "-q", "-b", "wt-2", "HEAD~1")
+	r.commit("d.txt", "d")
+	r.git("checkout", "-q", "main")
+	r.git("cherry-pick", "wt-2")
+
+	// wt-3: unmerged work
+	r.git("checkout", "-q", "-b", "wt-3")
+	r.commit("e.txt", "e")
+	r.git("checkout", "-q", "main")
+
+	tests := []struct {
+		branch string
+		want   bool
+	}{
+		{"wt-1", true},
+		{"wt-2", true},
+		{"wt-3", false},
+	}
+	for _, tt := range tests {
+		got, err := c.SquashMerged("main", tt.branch)
+		if err != nil {
+			t.Fatalf("SquashMerged(%s): %v", tt.branch, err)
+		}
+		if got != tt.want {
+			t.Errorf("SquashMerged(%s) = %v, want %v", tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestUnpushedCommits(t *testing.T) {
+	r := newTestRepo(t)
+	c := NewExecClient()
+
+	r.git("checkout", "-q", "-b", "wt-1")
+	r.commit("b.txt", "b")
+	prHead := strings.TrimSpace(r.git("rev-parse", "HEAD"))
+	// Pretend b was pushed: a remote-tracking ref points at it.
+	r.git("update-ref", "refs/remotes/origin/wt-1", prHead)
+	r.commit("c.txt", "c")
+	r.commit("d.txt", "d")
+
+	n, err := c.UnpushedCommits("wt-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("unpushed = %d, want 2", n)
+	}
+
+	// The remote branch was deleted after merge, but the PR head is known.
+	r.git("update-ref", "-d", "refs/remotes/origin/wt-1")
+	n, err = c.UnpushedCommits("wt-1", prHead, "0123456789abcdef0123456789abcdef01234567")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("unpushed with PR head = %d, want 2", n)
+	}
+}
+
+func TestIsAncestor(t *testing.T) {
+	r := newTestRepo(t)
+	c := NewExecClient()
+
+	base := strings.TrimSpace(r.git("rev-parse", "HEAD"))
+	r.git("checkout", "-q", "-b", "wt-1")
+	r.commit("b.txt", "b")
+	tip := strings.TrimSpace(r.git("rev-parse", "HEAD"))
+
+	for _, tt := range []struct {
+		commit, ref string
+		want        bool
+	}{
+		{base, "refs/heads/wt-1", true},
+		{tip, "refs/heads/wt-1", true},
+		{tip, "refs/heads/main", false},
+	} {
+		if got, err := c.IsAncestor(tt.commit, tt.ref); err != nil || got != tt.want {
+			t.Errorf("IsAncestor(%s, %s) = %v, %v; want %v", tt.commit, tt.ref, got, err, tt.want)
+		}
+	}
+	if _, err := c.IsAncestor("0123456789abcdef0123456789abcdef01234567", "refs/heads/wt-1"); err == nil {
+		t.Error("expected an error for an unknown commit")
+	}
+}
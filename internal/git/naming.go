@@ -0,0 +1,134 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultNamingTemplate is the naming template used when none is configured.
+const DefaultNamingTemplate = "wt-{n}"
+
+// Naming describes how numbered worktree branches are named. A template
+// such as "wt-{n}" or "agent/{user}/{n}" contains exactly one {n}
+// placeholder for the number; {user} expands to $USER, or the login name
+// when $USER is unset.
+type Naming struct {
+	prefix string // everything before {n}, with {user} expanded
+	suffix string // everything after {n}
+	re     *regexp.Regexp
+	dirRe  *regexp.Regexp
+}
+
+var defaultNaming = MustParseNaming(DefaultNamingTemplate)
+
+// DefaultNaming returns the built-in wt-{n} naming scheme.
+func DefaultNaming() *Naming {
+	return defaultNaming
+}
+
+// ParseNaming compiles a naming template.
+func ParseNaming(template string) (*Naming, error) {
+	if strings.Count(template, "{n}") != 1 {
+		return nil, fmt.Errorf("naming template %q must contain {n} exactly once", template)
+	}
+	expanded := template
+	if strings.Contains(template, "{user}") {
+		expanded = strings.ReplaceAll(template, "{user}", userName())
+	}
+	prefix, suffix, _ := strings.Cut(expanded, "{n}")
+	if strings.ContainsAny(prefix+suffix, "*?[\\ ") {
+		return nil, fmt.Errorf("naming template %q contains glob or whitespace characters", template)
+	}
+	for _, part := range strings.Split(prefix+"0"+suffix, "/") {
+		if part == "" {
+			return nil, fmt.Errorf("naming template %q expands to %q, which has an empty path component", template, expanded)
+		}
+	}
+	return &Naming{
+		prefix: prefix,
+		suffix: suffix,
+		re:     regexp.MustCompile(`^` + regexp.QuoteMeta(prefix) + `(\d+)` + regexp.QuoteMeta(suffix) + `$`),
+		dirRe:  regexp.MustCompile(`^` + regexp.QuoteMeta(SanitizeBranch(prefix)) + `(\d+)` + regexp.QuoteMeta(SanitizeBranch(suffix)) + `$`),
+	}, nil
+}
+
+// userName returns $USER, falling back to the login name of the current
+// user, since cron jobs and containers often leave $USER unset.
+func userName() string {
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// MustParseNaming is like ParseNaming but panics on an invalid template.
+func MustParseNaming(template string) *Naming {
+	n, err := ParseNaming(template)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Branch returns the branch name for number num.
+func (n *Naming) Branch(num int) string {
+	return n.prefix + strconv.Itoa(num) + n.suffix
+}
+
+// Num extracts the number from a branch name. Returns -1 if not matching.
+func (n *Naming) Num(branch string) int {
+	return matchNum(n.re, branch)
+}
+
+// Glob returns a pattern matching all branch names of this scheme, suitable
+// for `git branch --list` and `git for-each-ref`.
+func (n *Naming) Glob() string {
+	return n.prefix + "*" + n.suffix
+}
+
+// Filter returns only the branches that match the naming scheme.
+func (n *Naming) Filter(branches []string) []string {
+	var result []string
+	for _, b := range branches {
+		if n.Num(b) >= 0 {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// DirPrefix returns the sanitized prefix used in worktree directory names.
+func (n *Naming) DirPrefix() string {
+	return SanitizeBranch(n.prefix)
+}
+
+// DirNum extracts the number from a sanitized branch name as it appears in
+// a worktree directory name. Returns -1 if not matching.
+func (n *Naming) DirNum(name string) int {
+	return matchNum(n.dirRe, name)
+}
+
+// SanitizeBranch makes a branch name safe for use in a directory name,
+// matching worktrunk's convention of replacing path separators with "-".
+func SanitizeBranch(branch string) string {
+	return strings.NewReplacer("/", "-", "\\", "-").Replace(branch)
+}
+
+func matchNum(re *regexp.Regexp, s string) int {
+	m := re.FindStringSubmatch(s)
+	if m == nil {
+		return -1
+	}
+	num, err := strconv.Atoi(m[1])
+	if err != nil {
+		return -1
+	}
+	return num
+}
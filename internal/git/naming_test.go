@@ -0,0 +1,84 @@
+package git
+
+import (
+	"os/user"
+	"testing"
+)
+
+func TestParseNaming_Invalid(t *testing.T) {
+	for _, tmpl := range []string{"wt", "wt-{n}-{n}", "wt-*-{n}", "wt {n}", "agent//{n}", "/wt-{n}", "wt-{n}/"} {
+		if _, err := ParseNaming(tmpl); err == nil {
+			t.Errorf("ParseNaming(%q): expected error", tmpl)
+		}
+	}
+}
+
+func TestNaming_UserTemplate(t *testing.T) {
+	t.Setenv("USER", "nate")
+	n, err := ParseNaming("agent/{user}/{n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := n.Branch(7); got != "agent/nate/7" {
+		t.Errorf("Branch(7) = %q, want agent/nate/7", got)
+	}
+	if got := n.Glob(); got != "agent/nate/*" {
+		t.Errorf("Glob() = %q, want agent/nate/*", got)
+	}
+	if got := n.DirPrefix(); got != "agent-nate-" {
+		t.Errorf("DirPrefix() = %q, want agent-nate-", got)
+	}
+
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"agent/nate/7", 7},
+		{"agent/nate/0", 0},
+		{"agent/other/7", -1},
+		{"agent/nate/7/x", -1},
+		{"wt-7", -1},
+	}
+	for _, tt := range tests {
+		if got := n.Num(tt.name); got != tt.want {
+			t.Errorf("Num(%q) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if got := n.DirNum("agent-nate-12"); got != 12 {
+		t.Errorf("DirNum(agent-nate-12) = %d, want 12", got)
+	}
+	if got := n.DirNum("agent/nate/12"); got != -1 {
+		t.Errorf("DirNum(agent/nate/12) = %d, want -1", got)
+	}
+}
+
+func TestNaming_UserWithoutEnv(t *testing.T) {
+	u, err := user.Current()
+	if err != nil || u.Username == "" {
+		t.Skip("no current user")
+	}
+	t.Setenv("USER", "")
+	n, err := ParseNaming("agent/{user}/{n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n.Branch(1), "agent/"+u.Username+"/1"; got != want {
+		t.Errorf("Branch(1) = %q, want %q", got, want)
+	}
+}
+
+func TestNaming_Suffix(t *testing.T) {
+	n := MustParseNaming("task-{n}-wip")
+	if got := n.Branch(3); got != "task-3-wip" {
+		t.Errorf("Branch(3) = %q, want task-3-wip", got)
+	}
+	if got := n.Num("task-3-wip"); got != 3 {
+		t.Errorf("Num(task-3-wip) = %d, want 3", got)
+	}
+	got := n.Filter([]string{"task-1-wip", "task-2", "main", "task-10-wip"})
+	if len(got) != 2 || got[0] != "task-1-wip" || got[1] != "task-10-wip" {
+		t.Errorf("Filter = %v", got)
+	}
+}
@@ -1,16 +1,15 @@
 package git
 
 import (
-	"regexp"
-	"strconv"
 	"strings"
 )
 
 // Worktree represents a parsed worktree entry from `git worktree list --porcelain`.
 type Worktree struct {
-	Path   string
-	Branch string // short name, e.g. "wt-42" (empty if detached)
-	Bare   bool
+	Path     string
+	Branch   string // short name, e.g. "wt-42" (empty if detached)
+	Bare     bool
+	Prunable bool // git considers the entry stale, e.g. its directory is gone
 }
 
 // ParseWorktreeList parses `git worktree list --porcelain` output into Worktree structs.
@@ -27,6 +26,8 @@ func ParseWorktreeList(output string) []Worktree {
 			current.Branch = strings.TrimPrefix(line, "branch refs/heads/")
 		case line == "bare":
 			current.Bare = true
+		case line == "prunable" || strings.HasPrefix(line, "prunable "):
+			current.Prunable = true
 		case line == "":
 			if current.Path != "" {
 				worktrees = append(worktrees, current)
@@ -41,30 +42,14 @@ func ParseWorktreeList(output string) []Worktree {
 	return worktrees
 }
 
-var wtNumRe = regexp.MustCompile(`^wt-(\d+)$`)
-
 // ExtractWtNum extracts the number N from a "wt-N" branch name. Returns -1 if not matching.
 func ExtractWtNum(name string) int {
-	m := wtNumRe.FindStringSubmatch(name)
-	if m == nil {
-		return -1
-	}
-	n, err := strconv.Atoi(m[1])
-	if err != nil {
-		return -1
-	}
-	return n
+	return defaultNaming.Num(name)
 }
 
 // FilterWtBranches filters a list of branch names to only wt-N branches.
 func FilterWtBranches(branches []string) []string {
-	var result []string
-	for _, b := range branches {
-		if ExtractWtNum(b) >= 0 {
-			result = append(result, b)
-		}
-	}
-	return result
+	return defaultNaming.Filter(branches)
 }
 
 // WorktreesByBranch builds a map from branch name to Worktree.
@@ -77,3 +62,60 @@ func WorktreesByBranch(worktrees []Worktree) map[string]Worktree {
 	}
 	return m
 }
+
+// StashListFormat is the `git stash list --format` that ParseStashList reads:
+// reflog selector, commit, parents and subject, tab-separated.
+const StashListFormat = "%gd%x09%H%x09%P%x09%gs"
+
+// Stash is an entry of `git stash list`.
+type Stash struct {
+	Ref    string // e.g. "stash@{0}"
+	SHA    string
+	Base   string // the commit HEAD was at when the stash was made
+	Branch string // branch the stash was made on; empty if detached or unknown
+}
+
+// ParseStashList parses `git stash list` output in StashListFormat. The
+// branch comes from the default "WIP on <branch>:" or "On <branch>:" subject.
+func ParseStashList(output string) []Stash {
+	var stashes []Stash
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(strings.TrimRight(line, "\r"), "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		s := Stash{Ref: fields[0], SHA: fields[1]}
+		s.Base, _, _ = strings.Cut(fields[2], " ")
+		rest, ok := strings.CutPrefix(fields[3], "WIP on ")
+		if !ok {
+			rest, ok = strings.CutPrefix(fields[3], "On ")
+		}
+		if branch, _, found := strings.Cut(rest, ": "); ok && found && branch != "(no branch)" {
+			s.Branch = branch
+		}
+		stashes = append(stashes, s)
+	}
+	return stashes
+}
+
+// RefListFormat is the `git for-each-ref --format` that ParseRefList reads.
+const RefListFormat = "%(objectname) %(refname)"
+
+// Ref is a fully qualified ref and the object it points at.
+type Ref struct {
+	Name string // e.g. "refs/heads/main"
+	SHA  string
+}
+
+// ParseRefList parses `git for-each-ref` output in RefListFormat.
+func ParseRefList(output string) []Ref {
+	var refs []Ref
+	for _, line := range nonEmpty(strings.Split(output, "\n")) {
+		sha, name, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		refs = append(refs, Ref{Name: name, SHA: sha})
+	}
+	return refs
+}
@@ -54,6 +54,16 @@ detached
 	}
 }
 
+func TestParseWorktreeList_Prunable(t *testing.T) {
+	input := "worktree /src/repo\nHEAD abc\nbranch refs/heads/main\n\n" +
+		"worktree /src/repo.wt-1\nHEAD def\nbranch refs/heads/wt-1\nprunable gitdir file points to non-existent location\n\n"
+
+	wts := ParseWorktreeList(input)
+	if len(wts) != 2 || wts[0].Prunable || !wts[1].Prunable {
+		t.Errorf("worktrees = %+v, want only wt-1 prunable", wts)
+	}
+}
+
 func TestParseWorktreeListNoTrailingNewline(t *testing.T) {
 	input := `worktree /Users/nate/gh/repo
 HEAD abc123
@@ -123,3 +133,46 @@ func TestWorktreesByBranch(t *testing.T) {
 		t.Errorf("wt-2 path = %q, want /b", m["wt-2"].Path)
 	}
 }
+
+func TestParseStashList(t *testing.T) {
+	input := "stash@{0}\tabc\tb0 i0\tWIP on wt-3: 1234567 fix tests\n" +
+		"stash@{1}\tdef\tb1 i1 u1\tOn agent/x/4: before rebase\n" +
+		"stash@{2}\t123\tb2 i2\tWIP on (no branch): 1234567 detached\n" +
+		"stash@{3}\t456\tb3 i3\tautostash\n"
+
+	got := ParseStashList(input)
+	want := []Stash{
+		{Ref: "stash@{0}", SHA: "abc", Base: "b0", Branch: "wt-3"},
+		{Ref: "stash@{1}", SHA: "def", Base: "b1", Branch: "agent/x/4"},
+		{Ref: "stash@{2}", SHA: "123", Base: "b2"},
+		{Ref: "stash@{3}", SHA: "456", Base: "b3"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d stashes, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("stash[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseRefList(t *testing.T) {
+	input := "abc123 refs/wt-cycle/archive/wt-1/1700000000\n" +
+		"def456 refs/wt-cycle/archive/agent/bob/2/1700000100\n" +
+		"\n"
+
+	got := ParseRefList(input)
+	want := []Ref{
+		{Name: "refs/wt-cycle/archive/wt-1/1700000000", SHA: "abc123"},
+		{Name: "refs/wt-cycle/archive/agent/bob/2/1700000100", SHA: "def456"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d refs, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ref[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package git
+
+import "strings"
+
+// readOnlyCommands never change refs, the index or worktrees.
+var readOnlyCommands = map[string]bool{
+	"cat-file":     true,
+	"cherry":       true,
+	"commit-tree":  true, // writes an unreferenced object only
+	"diff":         true,
+	"for-each-ref": true,
+	"log":          true,
+	"ls-files":     true,
+	"merge-base":   true,
+	"rev-list":     true,
+	"rev-parse":    true,
+	"show":         true,
+	"status":       true,
+}
+
+// SplitDir separates leading `-C <dir>` options from args. dir is empty
+// when the command runs in the current directory.
+func SplitDir(args []string) (dir string, rest []string) {
+	for len(args) >= 2 && args[0] == "-C" {
+		dir, args = args[1], args[2:]
+	}
+	return dir, args
+}
+
+// IsReadOnly reports whether the git command args, as passed to
+// Client.Run, only reads repository state. Unknown commands count as
+// mutations.
+func IsReadOnly(args []string) bool {
+	_, args = SplitDir(args)
+	if len(args) == 0 {
+		return true
+	}
+	cmd, rest := args[0], args[1:]
+	if readOnlyCommands[cmd] {
+		return true
+	}
+	switch cmd {
+	case "branch":
+		return readOnlyBranch(rest)
+	case "worktree", "stash":
+		return len(rest) > 0 && (rest[0] == "list" || rest[0] == "show")
+	case "remote":
+		return len(rest) == 0 || rest[0] == "-v" || rest[0] == "get-url" || rest[0] == "show"
+	case "config":
+		for _, a := range rest {
+			if a == "--get" || a == "--get-all" || a == "--get-regexp" || a == "--list" || a == "-l" {
+				return true
+			}
+		}
+		return false
+	case "symbolic-ref":
+		return len(positional(rest)) <= 1
+	}
+	return false
+}
+
+// readOnlyBranch reports whether `git branch <args>` only lists branches.
+func readOnlyBranch(args []string) bool {
+	for _, a := range args {
+		switch a {
+		case "--show-current", "--list", "-l", "--merged", "--no-merged", "--contains", "--no-contains":
+			return true
+		case "-d", "-D", "--delete", "-m", "-M", "--move", "-c", "-C", "--copy",
+			"-f", "--force", "-u", "--unset-upstream", "--edit-description":
+			return false
+		}
+		if strings.HasPrefix(a, "--set-upstream-to") {
+			return false
+		}
+	}
+	// Without list options, a branch name creates that branch.
+	return len(positional(args)) == 0
+}
+
+// positional returns the arguments that are not options.
+func positional(args []string) []string {
+	var out []string
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			out = append(out, a)
+		}
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsReadOnly(t *testing.T) {
+	tests := []struct {
+		args string
+		want bool
+	}{
+		{"rev-parse --show-toplevel", true},
+		{"branch --show-current", true},
+		{"branch --merged origin/main --list wt-* --format=%(refname:short)", true},
+		{"branch --format=%(refname:short)", true},
+		{"branch -D wt-1", false},
+		{"branch wt-1", false},
+		{"worktree list --porcelain", true},
+		{"worktree remove /tmp/wt-1", false},
+		{"worktree add -q -b wt-2 /tmp/wt-2 origin/main", false},
+		{"stash list --format=%gd", true},
+		{"stash drop -q stash@{0}", false},
+		{"remote get-url origin", true},
+		{"symbolic-ref --short refs/remotes/origin/HEAD", true},
+		{"symbolic-ref HEAD refs/heads/main", false},
+		{"-C /tmp/wt-1 checkout -q --detach origin/main", false},
+		{"-C /tmp/wt-1 status --porcelain", true},
+		{"checkout -q -b wt-3", false},
+		{"update-ref refs/wt-cycle/archive/wt-1/1 abc", false},
+		{"commit-tree abc -p def -m probe", true},
+		{"gc", false},
+	}
+	for _, tt := range tests {
+		if got := IsReadOnly(strings.Fields(tt.args)); got != tt.want {
+			t.Errorf("IsReadOnly(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSplitDir(t *testing.T) {
+	dir, rest := SplitDir([]string{"-C", "/tmp/wt-1", "checkout", "-q"})
+	if dir != "/tmp/wt-1" || strings.Join(rest, " ") != "checkout -q" {
+		t.Errorf("SplitDir = %q, %v", dir, rest)
+	}
+}
@@ -0,0 +1,122 @@
+package gitea
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/forge"
+)
+
+// Client implements forge.BranchProvider against the Gitea API, which Forgejo
+// and Codeberg share.
+type Client struct {
+	APIURL string // e.g. https://codeberg.org/api/v1
+	Owner  string
+	Repo   string
+	Token  string
+	HTTP   *http.Client
+}
+
+// NewClient creates a client for the repository at remoteURL. An empty
+// apiURL selects https://<remote host>/api/v1. The token is read from
+// GITEA_TOKEN, then FORGEJO_TOKEN.
+func NewClient(remoteURL, apiURL string) (*Client, error) {
+	host, path, err := forge.ParseRemoteURL(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	owner, repo, ok := strings.Cut(path, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("%q is not an owner/repo path", path)
+	}
+	if apiURL == "" {
+		apiURL = "https://" + host + "/api/v1"
+	}
+	token := os.Getenv("GITEA_TOKEN")
+	if token == "" {
+		token = os.Getenv("FORGEJO_TOKEN")
+	}
+	return &Client{
+		APIURL: apiURL,
+		Owner:  owner,
+		Repo:   repo,
+		Token:  token,
+		HTTP:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+type pullRequest struct {
+	Head struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Merged bool `json:"merged"`
+}
+
+func (c *Client) ClosedPRs() ([]forge.PR, error) {
+	var prs []forge.PR
+	err := c.eachClosedPage(func(page []forge.PR) bool {
+		prs = append(prs, page...)
+		return true
+	})
+	return prs, err
+}
+
+// ClosedPRsFor returns the closed pull requests of branches. Gitea cannot
+// filter the list by head branch, so it pages through the most recently
+// updated first and stops once each branch has turned up.
+func (c *Client) ClosedPRsFor(branches []string) ([]forge.PR, error) {
+	wanted := make(map[string]bool, len(branches))
+	unseen := make(map[string]bool, len(branches))
+	for _, b := range branches {
+		wanted[b], unseen[b] = true, true
+	}
+	var prs []forge.PR
+	err := c.eachClosedPage(func(page []forge.PR) bool {
+		for _, pr := range page {
+			if wanted[pr.Branch] {
+				prs = append(prs, pr)
+				delete(unseen, pr.Branch)
+			}
+		}
+		return len(unseen) > 0
+	})
+	return prs, err
+}
+
+// eachClosedPage calls fn with each page of closed pull requests, most
+// recently updated first, until fn returns false or the pages run out.
+func (c *Client) eachClosedPage(fn func(page []forge.PR) bool) error {
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	if c.Token != "" {
+		header.Set("Authorization", "token "+c.Token)
+	}
+
+	// state=closed includes merged pull requests. Gitea caps limit at the
+	// server's MAX_RESPONSE_ITEMS (50 by default) and links the next page.
+	next := fmt.Sprintf("%s/repos/%s/%s/pulls?state=closed&sort=recentupdate&limit=50", c.APIURL, c.Owner, c.Repo)
+	for next != "" {
+		var page []pullRequest
+		var err error
+		next, err = forge.GetPage(c.HTTP, next, "Gitea API", header, &page)
+		if err != nil {
+			return err
+		}
+		prs := make([]forge.PR, 0, len(page))
+		for _, pr := range page {
+			state := forge.StateClosed
+			if pr.Merged {
+				state = forge.StateMerged
+			}
+			prs = append(prs, forge.PR{Branch: pr.Head.Ref, State: state, HeadSHA: pr.Head.SHA})
+		}
+		if !fn(prs) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package gitea
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/forge"
+)
+
+func TestClosedPRs(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/acme/widgets/pulls" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "token secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if r.URL.Query().Get("state") != "closed" {
+			t.Errorf("state = %q, want closed", r.URL.Query().Get("state"))
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v1/repos/acme/widgets/pulls?state=closed&limit=50&page=2>; rel="next"`, srv.URL))
+			fmt.Fprint(w, `[{"head": {"ref": "wt-1", "sha": "aaa"}, "merged": true}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"head": {"ref": "wt-2"}, "merged": false}]`)
+	}))
+	defer srv.Close()
+
+	c := &Client{APIURL: srv.URL + "/api/v1", Owner: "acme", Repo: "widgets", Token: "secret"}
+	got, err := c.ClosedPRs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []forge.PR{
+		{Branch: "wt-1", State: forge.StateMerged, HeadSHA: "aaa"},
+		{Branch: "wt-2", State: forge.StateClosed},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("PRs = %v, want %v", got, want)
+	}
+}
+
+func TestClosedPRsFor_StopsOnceAllBranchesSeen(t *testing.T) {
+	var srv *httptest.Server
+	var pages []string
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		switch page {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v1/repos/acme/widgets/pulls?state=closed&page=2>; rel="next"`, srv.URL))
+			fmt.Fprint(w, `[{"head": {"ref": "wt-2", "sha": "bbb"}, "merged": true}, {"head": {"ref": "feature"}, "merged": true}]`)
+		case "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v1/repos/acme/widgets/pulls?state=closed&page=3>; rel="next"`, srv.URL))
+			fmt.Fprint(w, `[{"head": {"ref": "wt-1", "sha": "aaa"}, "merged": false}, {"head": {"ref": "wt-2", "sha": "ccc"}, "merged": false}]`)
+		default:
+			t.Errorf("fetched page %q after every branch was seen", page)
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{APIURL: srv.URL + "/api/v1", Owner: "acme", Repo: "widgets"}
+	got, err := c.ClosedPRsFor([]string{"wt-1", "wt-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []forge.PR{
+		{Branch: "wt-2", State: forge.StateMerged, HeadSHA: "bbb"},
+		{Branch: "wt-1", State: forge.StateClosed, HeadSHA: "aaa"},
+		{Branch: "wt-2", State: forge.StateClosed, HeadSHA: "ccc"},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("PRs = %v, want %v", got, want)
+	}
+	if len(pages) != 2 {
+		t.Errorf("fetched pages %q, want 2", pages)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	t.Setenv("GITEA_TOKEN", "")
+	t.Setenv("FORGEJO_TOKEN", "fj")
+
+	c, err := NewClient("https://codeberg.org/acme/widgets.git", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.APIURL != "https://codeberg.org/api/v1" || c.Owner != "acme" || c.Repo != "widgets" || c.Token != "fj" {
+		t.Errorf("client = %+v", c)
+	}
+
+	if _, err := NewClient("https://codeberg.org/acme", ""); err == nil {
+		t.Error("expected error for a path without a repo")
+	}
+}
@@ -5,15 +5,11 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
-)
 
-// Client abstracts GitHub API operations.
-type Client interface {
-	// ClosedPRBranches returns branch names for all non-OPEN PRs in the repo.
-	ClosedPRBranches() ([]string, error)
-}
+	"github.com/sestinj/wt-cycle/internal/forge"
+)
 
-// GHClient implements Client by shelling out to `gh`.
+// GHClient implements forge.Provider by shelling out to `gh`.
 type GHClient struct{}
 
 func NewGHClient() *GHClient {
@@ -22,13 +18,14 @@ func NewGHClient() *GHClient {
 
 type prEntry struct {
 	HeadRefName string `json:"headRefName"`
+	HeadRefOid  string `json:"headRefOid"`
 	State       string `json:"state"`
 }
 
-func (c *GHClient) ClosedPRBranches() ([]string, error) {
+func (c *GHClient) ClosedPRs() ([]forge.PR, error) {
 	cmd := exec.Command("gh", "pr", "list",
 		"--state", "all",
-		"--json", "headRefName,state",
+		"--json", "headRefName,headRefOid,state",
 		"--limit", "500",
 	)
 	out, err := cmd.Output()
@@ -38,20 +35,23 @@ func (c *GHClient) ClosedPRBranches() ([]string, error) {
 		}
 		return nil, fmt.Errorf("gh pr list: %w", err)
 	}
-	return ParseClosedPRBranches(out)
+	return ParseClosedPRs(out)
 }
 
-// ParseClosedPRBranches extracts branch names for non-OPEN PRs from gh JSON output.
-func ParseClosedPRBranches(data []byte) ([]string, error) {
+// ParseClosedPRs extracts the merged and closed PRs from gh JSON output.
+func ParseClosedPRs(data []byte) ([]forge.PR, error) {
 	var entries []prEntry
 	if err := json.Unmarshal(data, &entries); err != nil {
 		return nil, fmt.Errorf("parsing gh output: %w", err)
 	}
-	var branches []string
+	var prs []forge.PR
 	for _, e := range entries {
-		if strings.ToUpper(e.State) != "OPEN" {
-			branches = append(branches, e.HeadRefName)
+		switch strings.ToUpper(e.State) {
+		case "MERGED":
+			prs = append(prs, forge.PR{Branch: e.HeadRefName, State: forge.StateMerged, HeadSHA: e.HeadRefOid})
+		case "CLOSED":
+			prs = append(prs, forge.PR{Branch: e.HeadRefName, State: forge.StateClosed, HeadSHA: e.HeadRefOid})
 		}
 	}
-	return branches, nil
+	return prs, nil
 }
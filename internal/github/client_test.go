@@ -2,35 +2,41 @@ package github
 
 import (
 	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/forge"
 )
 
-func TestParseClosedPRBranches(t *testing.T) {
+func TestParseClosedPRs(t *testing.T) {
 	input := []byte(`[
-		{"headRefName": "wt-1", "state": "MERGED"},
+		{"headRefName": "wt-1", "headRefOid": "aaa", "state": "MERGED"},
 		{"headRefName": "wt-2", "state": "CLOSED"},
 		{"headRefName": "wt-3", "state": "OPEN"},
 		{"headRefName": "feature-x", "state": "MERGED"},
 		{"headRefName": "wt-10", "state": "OPEN"}
 	]`)
 
-	got, err := ParseClosedPRBranches(input)
+	got, err := ParseClosedPRs(input)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	want := []string{"wt-1", "wt-2", "feature-x"}
+	want := []forge.PR{
+		{Branch: "wt-1", State: forge.StateMerged, HeadSHA: "aaa"},
+		{Branch: "wt-2", State: forge.StateClosed},
+		{Branch: "feature-x", State: forge.StateMerged},
+	}
 	if len(got) != len(want) {
 		t.Fatalf("len = %d, want %d: %v", len(got), len(want), got)
 	}
 	for i, g := range got {
 		if g != want[i] {
-			t.Errorf("got[%d] = %q, want %q", i, g, want[i])
+			t.Errorf("got[%d] = %+v, want %+v", i, g, want[i])
 		}
 	}
 }
 
-func TestParseClosedPRBranchesEmpty(t *testing.T) {
-	got, err := ParseClosedPRBranches([]byte(`[]`))
+func TestParseClosedPRsEmpty(t *testing.T) {
+	got, err := ParseClosedPRs([]byte(`[]`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -39,8 +45,8 @@ func TestParseClosedPRBranchesEmpty(t *testing.T) {
 	}
 }
 
-func TestParseClosedPRBranchesInvalid(t *testing.T) {
-	_, err := ParseClosedPRBranches([]byte(`not json`))
+func TestParseClosedPRsInvalid(t *testing.T) {
+	_, err := ParseClosedPRs([]byte(`not json`))
 	if err == nil {
 		t.Fatal("expected error for invalid JSON")
 	}
@@ -0,0 +1,128 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/forge"
+)
+
+// DefaultAPIURL is the public GitHub REST endpoint.
+const DefaultAPIURL = "https://api.github.com"
+
+// RESTClient implements forge.BranchProvider against the GitHub REST API,
+// following Link pagination so no closed PR is missed regardless of repo size.
+type RESTClient struct {
+	APIURL string // e.g. https://api.github.com or https://ghe.example.com/api/v3
+	Owner  string
+	Repo   string
+	Token  string
+	HTTP   *http.Client
+}
+
+// NewRESTClient creates a client for the repository at remoteURL. An empty
+// apiURL selects api.github.com. The token is read from GITHUB_TOKEN, then
+// GH_TOKEN; without one only public repositories can be queried.
+func NewRESTClient(remoteURL, apiURL string) (*RESTClient, error) {
+	_, path, err := forge.ParseRemoteURL(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	owner, repo, err := splitOwnerRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	return &RESTClient{
+		APIURL: apiURL,
+		Owner:  owner,
+		Repo:   repo,
+		Token:  TokenFromEnv(),
+		HTTP:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// TokenFromEnv returns GITHUB_TOKEN, falling back to GH_TOKEN.
+func TokenFromEnv() string {
+	if t := os.Getenv("GITHUB_TOKEN"); t != "" {
+		return t
+	}
+	return os.Getenv("GH_TOKEN")
+}
+
+type restPR struct {
+	Head struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	MergedAt *string `json:"merged_at"`
+}
+
+func (c *RESTClient) ClosedPRs() ([]forge.PR, error) {
+	return c.closedPulls(url.Values{})
+}
+
+// ClosedPRsFor looks up the PRs of each branch, which avoids paging
+// through every closed PR of a large repository.
+func (c *RESTClient) ClosedPRsFor(branches []string) ([]forge.PR, error) {
+	var prs []forge.PR
+	for _, b := range branches {
+		page, err := c.closedPulls(url.Values{"head": {c.Owner + ":" + b}})
+		if err != nil {
+			return nil, err
+		}
+		prs = append(prs, page...)
+	}
+	return prs, nil
+}
+
+// closedPulls lists every closed PR matching query.
+func (c *RESTClient) closedPulls(query url.Values) ([]forge.PR, error) {
+	// state=closed covers both merged and closed-without-merge PRs.
+	query.Set("state", "closed")
+	query.Set("per_page", "100")
+	next := fmt.Sprintf("%s/repos/%s/%s/pulls?%s", c.APIURL, c.Owner, c.Repo, query.Encode())
+	var prs []forge.PR
+	for next != "" {
+		var page []restPR
+		var err error
+		next, err = c.get(next, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range page {
+			state := forge.StateClosed
+			if pr.MergedAt != nil {
+				state = forge.StateMerged
+			}
+			prs = append(prs, forge.PR{Branch: pr.Head.Ref, State: state, HeadSHA: pr.Head.SHA})
+		}
+	}
+	return prs, nil
+}
+
+// get fetches one page into v and returns the next page URL, if any.
+func (c *RESTClient) get(url string, v interface{}) (string, error) {
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github+json")
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.Token != "" {
+		header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return forge.GetPage(c.HTTP, url, "GitHub API", header, v)
+}
+
+// splitOwnerRepo splits a GitHub repository path into owner and name.
+func splitOwnerRepo(path string) (owner, repo string, err error) {
+	owner, repo, ok := strings.Cut(path, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", fmt.Errorf("%q is not an owner/repo path", path)
+	}
+	return owner, repo, nil
+}
@@ -0,0 +1,108 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/forge"
+)
+
+func TestRESTClient_Paginates(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/widgets/pulls" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if r.URL.Query().Get("state") != "closed" {
+			t.Errorf("state = %q, want closed", r.URL.Query().Get("state"))
+		}
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/acme/widgets/pulls?state=closed&page=2>; rel="next", <%s/repos/acme/widgets/pulls?state=closed&page=2>; rel="last"`, srv.URL, srv.URL))
+			fmt.Fprint(w, `[{"head": {"ref": "wt-1", "sha": "aaa"}, "merged_at": "2024-05-01T10:00:00Z"}, {"head": {"ref": "feature"}, "merged_at": null}]`)
+		case "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/acme/widgets/pulls?state=closed>; rel="prev"`, srv.URL))
+			fmt.Fprint(w, `[{"head": {"ref": "wt-2"}, "merged_at": "2024-04-01T10:00:00Z"}]`)
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	}))
+	defer srv.Close()
+
+	c := &RESTClient{APIURL: srv.URL, Owner: "acme", Repo: "widgets", Token: "secret"}
+	got, err := c.ClosedPRs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []forge.PR{
+		{Branch: "wt-1", State: forge.StateMerged, HeadSHA: "aaa"},
+		{Branch: "feature", State: forge.StateClosed},
+		{Branch: "wt-2", State: forge.StateMerged},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("PRs = %v, want %v", got, want)
+	}
+}
+
+func TestRESTClient_ClosedPRsFor(t *testing.T) {
+	var queried []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != "closed" {
+			t.Errorf("state = %q, want closed", q.Get("state"))
+		}
+		queried = append(queried, q.Get("head"))
+		switch q.Get("head") {
+		case "acme:wt-1":
+			fmt.Fprint(w, `[{"head": {"ref": "wt-1", "sha": "aaa"}, "merged_at": "2024-05-01T10:00:00Z"}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer srv.Close()
+
+	c := &RESTClient{APIURL: srv.URL, Owner: "acme", Repo: "widgets"}
+	got, err := c.ClosedPRsFor([]string{"wt-1", "wt-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []forge.PR{{Branch: "wt-1", State: forge.StateMerged, HeadSHA: "aaa"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("PRs = %v, want %v", got, want)
+	}
+	if fmt.Sprint(queried) != "[acme:wt-1 acme:wt-2]" {
+		t.Errorf("queried heads %v", queried)
+	}
+}
+
+func TestRESTClient_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	c := &RESTClient{APIURL: srv.URL, Owner: "acme", Repo: "widgets"}
+	_, err := c.ClosedPRs()
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "Bad credentials") {
+		t.Errorf("expected 401 error with message, got %v", err)
+	}
+}
+
+func TestNewRESTClient(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "from-gh")
+
+	c, err := NewRESTClient("git@github.com:acme/widgets.git", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Owner != "acme" || c.Repo != "widgets" || c.APIURL != DefaultAPIURL || c.Token != "from-gh" {
+		t.Errorf("client = %+v", c)
+	}
+}
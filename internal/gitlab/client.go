@@ -0,0 +1,109 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/forge"
+)
+
+// Client implements forge.Provider against the GitLab REST API (v4).
+type Client struct {
+	APIURL  string // e.g. https://gitlab.com/api/v4
+	Project string // full project path, e.g. "group/subgroup/repo"
+	Token   string
+	HTTP    *http.Client
+}
+
+// NewClient creates a client for the project at remoteURL. An empty
+// apiURL selects https://<remote host>/api/v4. The token is read from
+// GITLAB_TOKEN; without one only public projects can be queried.
+func NewClient(remoteURL, apiURL string) (*Client, error) {
+	host, path, err := forge.ParseRemoteURL(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	if apiURL == "" {
+		apiURL = "https://" + host + "/api/v4"
+	}
+	return &Client{
+		APIURL:  apiURL,
+		Project: path,
+		Token:   os.Getenv("GITLAB_TOKEN"),
+		HTTP:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+type mergeRequest struct {
+	SourceBranch string `json:"source_branch"`
+	SHA          string `json:"sha"`   // head of the source branch
+	State        string `json:"state"` // opened, closed, locked or merged
+}
+
+// states maps GitLab merge request states to forge states.
+var states = map[string]string{
+	"merged": forge.StateMerged,
+	"closed": forge.StateClosed,
+}
+
+func (c *Client) ClosedPRs() ([]forge.PR, error) {
+	var prs []forge.PR
+	for _, state := range []string{"merged", "closed"} {
+		mrs, err := c.mergeRequests(url.Values{"state": {state}})
+		if err != nil {
+			return nil, err
+		}
+		prs = appendClosed(prs, mrs)
+	}
+	return prs, nil
+}
+
+// ClosedPRsFor looks up the merge requests of each branch, which avoids
+// paging through every closed merge request of a large project.
+func (c *Client) ClosedPRsFor(branches []string) ([]forge.PR, error) {
+	var prs []forge.PR
+	for _, b := range branches {
+		mrs, err := c.mergeRequests(url.Values{"source_branch": {b}})
+		if err != nil {
+			return nil, err
+		}
+		prs = appendClosed(prs, mrs)
+	}
+	return prs, nil
+}
+
+// appendClosed adds the merged and closed merge requests in mrs to prs.
+func appendClosed(prs []forge.PR, mrs []mergeRequest) []forge.PR {
+	for _, mr := range mrs {
+		if state, ok := states[mr.State]; ok {
+			prs = append(prs, forge.PR{Branch: mr.SourceBranch, State: state, HeadSHA: mr.SHA})
+		}
+	}
+	return prs
+}
+
+// mergeRequests lists every merge request matching query.
+func (c *Client) mergeRequests(query url.Values) ([]mergeRequest, error) {
+	header := http.Header{}
+	if c.Token != "" {
+		header.Set("PRIVATE-TOKEN", c.Token)
+	}
+
+	query.Set("per_page", "100")
+	next := fmt.Sprintf("%s/projects/%s/merge_requests?%s",
+		c.APIURL, url.PathEscape(c.Project), query.Encode())
+	var all []mergeRequest
+	for next != "" {
+		var page []mergeRequest
+		var err error
+		next, err = forge.GetPage(c.HTTP, next, "GitLab API", header, &page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+	}
+	return all, nil
+}
@@ -0,0 +1,97 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/forge"
+)
+
+func TestClosedPRs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fsub%2Fwidgets/merge_requests" {
+			t.Errorf("path = %q", r.URL.EscapedPath())
+		}
+		if got := r.Header.Get("PRIVATE-TOKEN"); got != "secret" {
+			t.Errorf("PRIVATE-TOKEN = %q", got)
+		}
+		q := r.URL.Query()
+		switch q.Get("state") + "/" + q.Get("page") {
+		case "merged/":
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprint(w, `[{"source_branch": "wt-1", "sha": "aaa", "state": "merged"}]`)
+		case "merged/2":
+			fmt.Fprint(w, `[{"source_branch": "wt-2", "state": "merged"}]`)
+		case "closed/":
+			fmt.Fprint(w, `[{"source_branch": "wt-3", "state": "closed"}]`)
+		default:
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{APIURL: srv.URL + "/api/v4", Project: "group/sub/widgets", Token: "secret"}
+	got, err := c.ClosedPRs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []forge.PR{
+		{Branch: "wt-1", State: forge.StateMerged, HeadSHA: "aaa"},
+		{Branch: "wt-2", State: forge.StateMerged},
+		{Branch: "wt-3", State: forge.StateClosed},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("PRs = %v, want %v", got, want)
+	}
+}
+
+func TestClosedPRsFor(t *testing.T) {
+	var queried []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != "" {
+			t.Errorf("unexpected state filter in %q", r.URL.RawQuery)
+		}
+		queried = append(queried, q.Get("source_branch"))
+		switch q.Get("source_branch") {
+		case "wt-1":
+			fmt.Fprint(w, `[{"source_branch": "wt-1", "sha": "aaa", "state": "merged"}, {"source_branch": "wt-1", "state": "closed"}]`)
+		case "wt-2":
+			fmt.Fprint(w, `[{"source_branch": "wt-2", "state": "opened"}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{APIURL: srv.URL + "/api/v4", Project: "group/widgets"}
+	got, err := c.ClosedPRsFor([]string{"wt-1", "wt-2", "wt-3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []forge.PR{
+		{Branch: "wt-1", State: forge.StateMerged, HeadSHA: "aaa"},
+		{Branch: "wt-1", State: forge.StateClosed},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("PRs = %v, want %v", got, want)
+	}
+	if fmt.Sprint(queried) != "[wt-1 wt-2 wt-3]" {
+		t.Errorf("queried branches %v", queried)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "tok")
+
+	c, err := NewClient("git@gitlab.example.com:group/widgets.git", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.APIURL != "https://gitlab.example.com/api/v4" || c.Project != "group/widgets" || c.Token != "tok" {
+		t.Errorf("client = %+v", c)
+	}
+}
@@ -0,0 +1,85 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Hook actions, passed to the hook as WT_CYCLE_ACTION.
+const (
+	PostCreate  = "post-create"
+	PostRecycle = "post-recycle"
+	PreRemove   = "pre-remove"
+)
+
+// DefaultTimeout bounds a hook when no timeout is configured.
+const DefaultTimeout = 10 * time.Minute
+
+// Context describes the worktree a hook runs for.
+type Context struct {
+	Action string
+	Branch string // empty for detached pool slots
+	Path   string
+	Num    int // -1 when the branch has no number
+}
+
+// Runner runs shell hooks with `sh -c` inside the worktree.
+type Runner struct {
+	Commands map[string]string // action -> shell command
+	Timeout  time.Duration
+	Output   io.Writer                             // hook stdout and stderr; defaults to os.Stderr
+	Logf     func(format string, a ...interface{}) // announces each hook that runs; optional
+}
+
+// Run executes the hook for ctx.Action, if one is configured. The hook's
+// whole process group is killed if it outlives the timeout.
+func (r *Runner) Run(hc Context) error {
+	command := r.Commands[hc.Action]
+	if command == "" {
+		return nil
+	}
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	out := r.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	if r.Logf != nil {
+		r.Logf("🪝 Running %s hook in %s", hc.Action, hc.Path)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, "sh", "-c", command)
+	c.Dir = hc.Path
+	c.Stdout = out
+	c.Stderr = out
+	c.Env = append(os.Environ(),
+		"WT_CYCLE_ACTION="+hc.Action,
+		"WT_CYCLE_BRANCH="+hc.Branch,
+		"WT_CYCLE_PATH="+hc.Path,
+		"WT_CYCLE_NUM="+strconv.Itoa(hc.Num),
+	)
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error { return syscall.Kill(-c.Process.Pid, syscall.SIGKILL) }
+	c.WaitDelay = time.Second
+
+	err := c.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s hook timed out after %s", hc.Action, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s hook failed: %w", hc.Action, err)
+	}
+	return nil
+}
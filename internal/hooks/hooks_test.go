@@ -0,0 +1,63 @@
+package hooks
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_Env(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	r := &Runner{
+		Commands: map[string]string{PostCreate: `echo "$WT_CYCLE_ACTION $WT_CYCLE_BRANCH $WT_CYCLE_NUM $WT_CYCLE_PATH $(pwd -P)"`},
+		Output:   &out,
+	}
+
+	err := r.Run(Context{Action: PostCreate, Branch: "wt-3", Path: dir, Num: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(out.String())
+	if len(fields) != 5 || fields[0] != PostCreate || fields[1] != "wt-3" || fields[2] != "3" || fields[3] != dir {
+		t.Errorf("hook output = %q", out.String())
+	}
+	if want, _ := filepath.EvalSymlinks(dir); fields[4] != want {
+		t.Errorf("hook ran in %q, want %q", fields[4], want)
+	}
+}
+
+func TestRun_NotConfigured(t *testing.T) {
+	r := &Runner{}
+	if err := r.Run(Context{Action: PreRemove, Path: t.TempDir()}); err != nil {
+		t.Errorf("expected no error without a hook, got %v", err)
+	}
+}
+
+func TestRun_Failure(t *testing.T) {
+	r := &Runner{Commands: map[string]string{PreRemove: "exit 3"}, Output: &bytes.Buffer{}}
+
+	err := r.Run(Context{Action: PreRemove, Path: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "pre-remove hook failed: exit status 3") {
+		t.Errorf("expected exit status error, got %v", err)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	r := &Runner{
+		Commands: map[string]string{PostRecycle: "sleep 5 & wait"},
+		Timeout:  100 * time.Millisecond,
+		Output:   &bytes.Buffer{},
+	}
+
+	start := time.Now()
+	err := r.Run(Context{Action: PostRecycle, Path: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("hook was not killed promptly (%s)", elapsed)
+	}
+}
@@ -0,0 +1,197 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sestinj/wt-cycle/internal/git"
+)
+
+// Git wraps a git.Client and journals every command that changes the
+// repository, along with the branch, worktree and SHAs it touched.
+type Git struct {
+	git.Client
+	Journal *Journal
+}
+
+func (g *Git) Run(args ...string) (string, error) {
+	if git.IsReadOnly(args) {
+		return g.Client.Run(args...)
+	}
+	e := g.describe(args)
+	out, err := g.Client.Run(args...)
+	if err == nil {
+		g.fillNew(&e, args)
+	}
+	g.record(e, err)
+	return out, err
+}
+
+func (g *Git) UpdateRef(ref, sha string) error {
+	e := Entry{Tool: ToolGit, Args: []string{"update-ref", ref, sha}, Dir: cwd(), Ref: ref, OldSHA: g.resolve(ref), NewSHA: sha}
+	err := g.Client.UpdateRef(ref, sha)
+	g.record(e, err)
+	return err
+}
+
+func (g *Git) DeleteRef(ref string) error {
+	e := Entry{Tool: ToolGit, Args: []string{"update-ref", "-d", ref}, Dir: cwd(), Ref: ref, OldSHA: g.resolve(ref)}
+	err := g.Client.DeleteRef(ref)
+	g.record(e, err)
+	return err
+}
+
+// Wt wraps run, which invokes worktrunk's `wt`, so that its calls are
+// journaled too.
+func (g *Git) Wt(run func(args ...string) error) func(args ...string) error {
+	return func(args ...string) error {
+		e := Entry{Tool: ToolWt, Args: args, Dir: cwd()}
+		var create bool
+		switch {
+		case len(args) >= 3 && args[0] == "remove":
+			e.Branch = args[len(args)-1]
+			e.OldSHA = g.resolve("refs/heads/" + e.Branch)
+			e.Path = g.worktreePath(e.Branch)
+		case len(args) >= 3 && args[0] == "switch" && args[1] == "-c":
+			e.Branch, create = args[2], true
+		case len(args) >= 2 && args[0] == "switch":
+			e.Branch = args[1]
+			e.Path = g.worktreePath(e.Branch)
+		}
+		err := run(args...)
+		if err == nil && create {
+			e.NewSHA = g.resolve("refs/heads/" + e.Branch)
+			e.Path = g.worktreePath(e.Branch)
+		}
+		g.record(e, err)
+		return err
+	}
+}
+
+// describe fills in what args is about to change, before it runs.
+func (g *Git) describe(args []string) Entry {
+	dir, rest := git.SplitDir(args)
+	if dir == "" {
+		dir = cwd()
+	}
+	e := Entry{Tool: ToolGit, Args: args, Dir: dir}
+	if len(rest) < 2 {
+		return e
+	}
+	switch rest[0] {
+	case "branch":
+		if rest[1] == "-D" || rest[1] == "-d" || rest[1] == "--delete" {
+			e.Branch = rest[len(rest)-1]
+			e.OldSHA = g.resolve("refs/heads/" + e.Branch)
+		}
+	case "checkout", "switch":
+		e.Path = dir
+		e.Branch = flagValue(rest, "-b", "-B", "-c", "-C")
+		e.OldSHA = g.head(dir)
+	case "worktree":
+		switch rest[1] {
+		case "remove":
+			e.Path = rest[len(rest)-1]
+			e.Branch = g.worktreeBranch(e.Path)
+			e.OldSHA = g.head(e.Path)
+		case "add":
+			e.Branch = flagValue(rest[2:], "-b", "-B")
+			if p := positional(rest[2:]); len(p) > 0 {
+				e.Path = p[0]
+			}
+		case "move":
+			e.Path = rest[len(rest)-1]
+		}
+	case "stash":
+		if rest[1] == "drop" {
+			e.Ref = rest[len(rest)-1]
+			e.OldSHA = g.resolve(e.Ref)
+		}
+	}
+	return e
+}
+
+// fillNew records where a checkout or new worktree ended up.
+func (g *Git) fillNew(e *Entry, args []string) {
+	_, rest := git.SplitDir(args)
+	switch {
+	case rest[0] == "checkout" || rest[0] == "switch":
+		e.NewSHA = g.head(e.Path)
+	case rest[0] == "worktree" && rest[1] == "add" && e.Path != "":
+		e.NewSHA = g.head(e.Path)
+	}
+}
+
+// record appends e. The journal is best effort: failing to write it must
+// never fail the operation being recorded.
+func (g *Git) record(e Entry, err error) {
+	if err != nil {
+		e.Error = err.Error()
+	}
+	_ = g.Journal.Append(e)
+}
+
+func (g *Git) resolve(ref string) string {
+	sha, _ := g.Client.ResolveRef(ref)
+	return sha
+}
+
+// head returns the commit checked out in the worktree at dir.
+func (g *Git) head(dir string) string {
+	sha, _ := g.Client.Run("-C", dir, "rev-parse", "--verify", "-q", "HEAD")
+	return sha
+}
+
+func (g *Git) worktreePath(branch string) string {
+	out, err := g.Client.WorktreeListPorcelain()
+	if err != nil {
+		return ""
+	}
+	return git.WorktreesByBranch(git.ParseWorktreeList(out))[branch].Path
+}
+
+func (g *Git) worktreeBranch(path string) string {
+	out, err := g.Client.WorktreeListPorcelain()
+	if err != nil {
+		return ""
+	}
+	for _, wt := range git.ParseWorktreeList(out) {
+		if filepath.Clean(wt.Path) == filepath.Clean(path) {
+			return wt.Branch
+		}
+	}
+	return ""
+}
+
+// flagValue returns the argument following the first of flags in args.
+func flagValue(args []string, flags ...string) string {
+	for i := 0; i+1 < len(args); i++ {
+		for _, f := range flags {
+			if args[i] == f {
+				return args[i+1]
+			}
+		}
+	}
+	return ""
+}
+
+// positional returns args that are neither options nor option values.
+func positional(args []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-b" || args[i] == "-B":
+			i++
+		case strings.HasPrefix(args[i], "-"):
+		default:
+			out = append(out, args[i])
+		}
+	}
+	return out
+}
+
+func cwd() string {
+	dir, _ := os.Getwd()
+	return dir
+}
@@ -0,0 +1,104 @@
+package journal
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/sestinj/wt-cycle/internal/git"
+)
+
+// fakeGit implements the parts of git.Client the wrapper uses.
+type fakeGit struct {
+	git.Client
+	shas  map[string]string
+	wts   string
+	calls [][]string
+}
+
+func (f *fakeGit) Run(args ...string) (string, error) {
+	f.calls = append(f.calls, args)
+	if len(args) == 6 && args[0] == "-C" && args[2] == "rev-parse" {
+		return f.shas[args[1]+":HEAD"], nil
+	}
+	return "", nil
+}
+
+func (f *fakeGit) ResolveRef(ref string) (string, error) {
+	if sha, ok := f.shas[ref]; ok {
+		return sha, nil
+	}
+	return "", fmt.Errorf("unknown ref %s", ref)
+}
+
+func (f *fakeGit) WorktreeListPorcelain() (string, error) { return f.wts, nil }
+func (f *fakeGit) UpdateRef(_, _ string) error            { return nil }
+
+func newTestGit(t *testing.T, f *fakeGit) (*Git, string) {
+	path := filepath.Join(t.TempDir(), FileName)
+	return &Git{Client: f, Journal: Open(path, "clean")}, path
+}
+
+func TestGit_RecordsMutationsOnly(t *testing.T) {
+	f := &fakeGit{
+		shas: map[string]string{"refs/heads/wt-1": "abc", "/src/repo.wt-1:HEAD": "abc"},
+		wts:  "worktree /src/repo.wt-1\nHEAD abc\nbranch refs/heads/wt-1\n\n",
+	}
+	g, path := newTestGit(t, f)
+
+	g.Run("rev-parse", "--show-toplevel")
+	g.Run("worktree", "remove", "/src/repo.wt-1")
+	g.Run("branch", "-D", "wt-1")
+	g.UpdateRef("refs/wt-cycle/archive/wt-1/1", "abc")
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(entries), entries)
+	}
+	if e := entries[0]; e.Path != "/src/repo.wt-1" || e.Branch != "wt-1" || e.OldSHA != "abc" {
+		t.Errorf("worktree remove entry = %+v", e)
+	}
+	if e := entries[1]; e.Branch != "wt-1" || e.OldSHA != "abc" {
+		t.Errorf("branch -D entry = %+v", e)
+	}
+	if e := entries[2]; e.Ref != "refs/wt-cycle/archive/wt-1/1" || e.NewSHA != "abc" || e.OldSHA != "" {
+		t.Errorf("update-ref entry = %+v", e)
+	}
+}
+
+func TestGit_CheckoutRecordsNewHead(t *testing.T) {
+	f := &fakeGit{shas: map[string]string{"/src/repo.wt-1:HEAD": "def"}}
+	g, path := newTestGit(t, f)
+
+	g.Run("-C", "/src/repo.wt-1", "checkout", "-q", "-b", "wt-2")
+
+	entries, _ := Read(path)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if e := entries[0]; e.Dir != "/src/repo.wt-1" || e.Branch != "wt-2" || e.NewSHA != "def" {
+		t.Errorf("checkout entry = %+v", e)
+	}
+}
+
+func TestGit_Wt(t *testing.T) {
+	f := &fakeGit{
+		shas: map[string]string{"refs/heads/wt-3": "abc"},
+		wts:  "worktree /src/repo.wt-3\nHEAD abc\nbranch refs/heads/wt-3\n\n",
+	}
+	g, path := newTestGit(t, f)
+
+	run := g.Wt(func(args ...string) error { return fmt.Errorf("boom") })
+	run("remove", "-y", "wt-3")
+
+	entries, _ := Read(path)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if e := entries[0]; e.Tool != ToolWt || e.Branch != "wt-3" || e.Path != "/src/repo.wt-3" || e.OldSHA != "abc" || e.Error != "boom" {
+		t.Errorf("wt entry = %+v", e)
+	}
+}
@@ -0,0 +1,149 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/state"
+)
+
+// FileName is the journal file inside the repo's state directory.
+const FileName = "journal.jsonl"
+
+// Tools that journal entries are recorded for.
+const (
+	ToolGit = "git"
+	ToolWt  = "wt"
+)
+
+// Entry records one mutating git or worktrunk command.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Op      string    `json:"op"`      // shared by every entry of one wt-cycle invocation
+	Command string    `json:"command"` // the wt-cycle subcommand, e.g. "clean"
+	Undoes  string    `json:"undoes,omitempty"`
+	Tool    string    `json:"tool"`
+	Args    []string  `json:"args"`
+	Dir     string    `json:"dir,omitempty"` // working directory the command ran in
+	Branch  string    `json:"branch,omitempty"`
+	Path    string    `json:"path,omitempty"` // worktree the command acted on
+	Ref     string    `json:"ref,omitempty"`
+	OldSHA  string    `json:"old_sha,omitempty"`
+	NewSHA  string    `json:"new_sha,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Journal appends entries for one invocation to an append-only JSONL file.
+type Journal struct {
+	path    string
+	op      string
+	command string
+	now     func() time.Time
+
+	// Undoes marks the entries written from now on as reversing that op.
+	Undoes string
+}
+
+// New opens the journal for the repo whose main worktree is repoRoot.
+func New(repoRoot, command string) *Journal {
+	return Open(filepath.Join(state.Dir(repoRoot), FileName), command)
+}
+
+// Open returns a journal writing to path. Every entry it appends carries a
+// fresh op id and the given command name.
+func Open(path, command string) *Journal {
+	now := time.Now()
+	op := strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.Itoa(os.Getpid())
+	return &Journal{path: path, op: op, command: command, now: time.Now}
+}
+
+// Path returns the journal file's location.
+func (j *Journal) Path() string { return j.path }
+
+// Append fills in e's time, op and command and writes it as one line.
+func (j *Journal) Append(e Entry) error {
+	e.Time = j.now()
+	e.Op = j.op
+	e.Command = j.command
+	e.Undoes = j.Undoes
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return err
+	}
+	// O_APPEND keeps lines from concurrent invocations intact.
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Entries reads the whole journal, oldest first. A missing journal is empty.
+func (j *Journal) Entries() ([]Entry, error) {
+	return Read(j.path)
+}
+
+// Read parses the journal at path. Lines that fail to parse, such as a
+// line cut short by a crash, are skipped.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Op groups the entries of one invocation.
+type Op struct {
+	ID      string
+	Command string
+	Time    time.Time // time of the first entry
+	Undoes  string
+	Entries []Entry
+}
+
+// Ops groups entries by op id, ordered by each op's first entry.
+func Ops(entries []Entry) []Op {
+	var ops []Op
+	index := make(map[string]int)
+	for _, e := range entries {
+		i, ok := index[e.Op]
+		if !ok {
+			i = len(ops)
+			index[e.Op] = i
+			ops = append(ops, Op{ID: e.Op, Command: e.Command, Time: e.Time, Undoes: e.Undoes})
+		}
+		ops[i].Entries = append(ops[i].Entries, e)
+	}
+	return ops
+}
@@ -0,0 +1,56 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", FileName)
+
+	clean := Open(path, "clean")
+	clean.Append(Entry{Tool: ToolGit, Args: []string{"branch", "-D", "wt-1"}, Branch: "wt-1", OldSHA: "abc"})
+	clean.Append(Entry{Tool: ToolGit, Args: []string{"branch", "-D", "wt-2"}, Branch: "wt-2", OldSHA: "def"})
+
+	// A torn line from a crashed writer must not hide the rest.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{\"time\":\n")
+	f.Close()
+
+	undo := Open(path, "undo")
+	undo.Undoes = "some-op"
+	undo.Append(Entry{Tool: ToolWt, Args: []string{"switch", "-c", "wt-1"}})
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(entries), entries)
+	}
+	if entries[0].Op == "" || entries[0].Op != entries[1].Op || entries[0].Op == entries[2].Op {
+		t.Errorf("op ids = %q %q %q", entries[0].Op, entries[1].Op, entries[2].Op)
+	}
+	if entries[1].Command != "clean" || entries[1].OldSHA != "def" || entries[1].Time.IsZero() {
+		t.Errorf("entry = %+v", entries[1])
+	}
+
+	ops := Ops(entries)
+	if len(ops) != 2 || ops[0].Command != "clean" || len(ops[0].Entries) != 2 {
+		t.Fatalf("ops = %+v", ops)
+	}
+	if ops[1].Command != "undo" || ops[1].Undoes != "some-op" {
+		t.Errorf("undo op = %+v", ops[1])
+	}
+}
+
+func TestRead_Missing(t *testing.T) {
+	entries, err := Read(filepath.Join(t.TempDir(), FileName))
+	if err != nil || entries != nil {
+		t.Errorf("Read = %v, %v; want nothing", entries, err)
+	}
+}
@@ -0,0 +1,176 @@
+package lease
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sestinj/wt-cycle/internal/procs"
+	"github.com/sestinj/wt-cycle/internal/state"
+)
+
+const DefaultTTL = 2 * time.Hour
+
+// Lease records that a worktree belongs to an owner (typically an agent).
+// A lease is live until its TTL elapses without a heartbeat or, when PID is
+// set, until that process exits.
+type Lease struct {
+	Branch    string        `json:"branch"`
+	Path      string        `json:"path"`
+	Owner     string        `json:"owner"`
+	PID       int           `json:"pid,omitempty"`
+	Acquired  time.Time     `json:"acquired"`
+	Heartbeat time.Time     `json:"heartbeat"`
+	TTL       time.Duration `json:"ttl"`
+}
+
+// Live reports whether the lease still protects its worktree at now.
+func (l *Lease) Live(now time.Time) bool {
+	if now.After(l.Heartbeat.Add(l.TTL)) {
+		return false
+	}
+	return l.PID == 0 || procs.Alive(l.PID)
+}
+
+// Describe formats the owner for display, e.g. "agent-1 (pid 123)".
+func (l *Lease) Describe() string {
+	if l.PID == 0 {
+		return l.Owner
+	}
+	return fmt.Sprintf("%s (pid %d)", l.Owner, l.PID)
+}
+
+// Store keeps one lease file per branch under the repo's state directory.
+type Store struct {
+	dir string
+	now func() time.Time
+}
+
+// New creates a lease store for the given repo root.
+func New(repoRoot string) *Store {
+	return &Store{dir: filepath.Join(state.Dir(repoRoot), "leases"), now: time.Now}
+}
+
+// Get returns the lease on branch, live or not, or nil if there is none.
+func (s *Store) Get(branch string) (*Lease, error) {
+	data, err := os.ReadFile(s.path(branch))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var l Lease
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("parsing lease for %s: %w", branch, err)
+	}
+	return &l, nil
+}
+
+// Active returns the live lease on branch, or nil if it is free.
+func (s *Store) Active(branch string) (*Lease, error) {
+	l, err := s.Get(branch)
+	if err != nil || l == nil || !l.Live(s.now()) {
+		return nil, err
+	}
+	return l, nil
+}
+
+// List returns all live leases.
+func (s *Store) List() ([]Lease, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var leases []Lease
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		branch, err := url.PathUnescape(name)
+		if err != nil {
+			continue
+		}
+		if l, err := s.Active(branch); err == nil && l != nil {
+			leases = append(leases, *l)
+		}
+	}
+	return leases, nil
+}
+
+// Claim takes a lease on l.Branch for l.Owner. Claiming a branch the same
+// owner already holds renews it; a live lease held by someone else is an error.
+func (s *Store) Claim(l Lease) error {
+	existing, err := s.Active(l.Branch)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Owner != l.Owner {
+		return fmt.Errorf("%s is leased by %s", l.Branch, existing.Describe())
+	}
+	now := s.now()
+	l.Acquired = now
+	if existing != nil {
+		l.Acquired = existing.Acquired
+	}
+	l.Heartbeat = now
+	if l.TTL <= 0 {
+		l.TTL = DefaultTTL
+	}
+	return s.write(l)
+}
+
+// Heartbeat extends a lease held by owner. A ttl of zero keeps the current TTL.
+func (s *Store) Heartbeat(branch, owner string, ttl time.Duration) error {
+	l, err := s.Active(branch)
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		return fmt.Errorf("%s has no active lease", branch)
+	}
+	if l.Owner != owner {
+		return fmt.Errorf("%s is leased by %s, not %s", branch, l.Describe(), owner)
+	}
+	l.Heartbeat = s.now()
+	if ttl > 0 {
+		l.TTL = ttl
+	}
+	return s.write(*l)
+}
+
+// Release drops the lease on branch. Unless force is set, a live lease
+// must be held by owner.
+func (s *Store) Release(branch, owner string, force bool) error {
+	l, err := s.Active(branch)
+	if err != nil {
+		return err
+	}
+	if l != nil && l.Owner != owner && !force {
+		return fmt.Errorf("%s is leased by %s, not %s", branch, l.Describe(), owner)
+	}
+	if err := os.Remove(s.path(branch)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) write(l Lease) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return state.WriteFileAtomic(s.path(l.Branch), data)
+}
+
+func (s *Store) path(branch string) string {
+	return filepath.Join(s.dir, url.PathEscape(branch)+".json")
+}
@@ -0,0 +1,118 @@
+package lease
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := &Store{dir: t.TempDir(), now: func() time.Time { return now }}
+	return s, &now
+}
+
+func TestClaimAndActive(t *testing.T) {
+	s, _ := testStore(t)
+
+	if err := s.Claim(Lease{Branch: "wt-1", Path: "/p", Owner: "agent-a"}); err != nil {
+		t.Fatal(err)
+	}
+	l, err := s.Active("wt-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l == nil || l.Owner != "agent-a" || l.TTL != DefaultTTL {
+		t.Fatalf("Active = %+v, want agent-a with default TTL", l)
+	}
+}
+
+func TestClaim_ConflictingOwner(t *testing.T) {
+	s, _ := testStore(t)
+	s.Claim(Lease{Branch: "wt-1", Owner: "agent-a"})
+
+	err := s.Claim(Lease{Branch: "wt-1", Owner: "agent-b"})
+	if err == nil || !strings.Contains(err.Error(), "agent-a") {
+		t.Fatalf("expected conflict naming agent-a, got %v", err)
+	}
+
+	// Same owner renews
+	if err := s.Claim(Lease{Branch: "wt-1", Owner: "agent-a"}); err != nil {
+		t.Fatalf("renew by same owner: %v", err)
+	}
+}
+
+func TestExpiryAndHeartbeat(t *testing.T) {
+	s, now := testStore(t)
+	s.Claim(Lease{Branch: "wt-1", Owner: "a", TTL: time.Minute})
+
+	*now = now.Add(50 * time.Second)
+	if err := s.Heartbeat("wt-1", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	*now = now.Add(50 * time.Second) // 100s after claim, 50s after heartbeat
+	if l, _ := s.Active("wt-1"); l == nil {
+		t.Fatal("lease should still be live after heartbeat")
+	}
+
+	*now = now.Add(2 * time.Minute)
+	if l, _ := s.Active("wt-1"); l != nil {
+		t.Fatalf("lease should have expired, got %+v", l)
+	}
+
+	// An expired lease can be taken over
+	if err := s.Claim(Lease{Branch: "wt-1", Owner: "b"}); err != nil {
+		t.Fatalf("claim after expiry: %v", err)
+	}
+}
+
+func TestDeadPIDReleasesLease(t *testing.T) {
+	s, _ := testStore(t)
+	s.Claim(Lease{Branch: "wt-1", Owner: "a", PID: 999999})
+
+	if l, _ := s.Active("wt-1"); l != nil {
+		t.Fatalf("lease with dead pid should not be live, got %+v", l)
+	}
+
+	s.Claim(Lease{Branch: "wt-2", Owner: "a", PID: os.Getpid()})
+	if l, _ := s.Active("wt-2"); l == nil {
+		t.Fatal("lease with live pid should be live")
+	}
+}
+
+func TestRelease(t *testing.T) {
+	s, _ := testStore(t)
+	s.Claim(Lease{Branch: "agent/x/1", Owner: "a"})
+
+	if err := s.Release("agent/x/1", "b", false); err == nil {
+		t.Fatal("expected error releasing someone else's lease")
+	}
+	if err := s.Release("agent/x/1", "b", true); err != nil {
+		t.Fatalf("forced release: %v", err)
+	}
+	if l, _ := s.Get("agent/x/1"); l != nil {
+		t.Fatalf("expected lease removed, got %+v", l)
+	}
+	// Releasing a free branch is fine
+	if err := s.Release("agent/x/1", "a", false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestList(t *testing.T) {
+	s, _ := testStore(t)
+	s.Claim(Lease{Branch: "wt-1", Owner: "a"})
+	s.Claim(Lease{Branch: "agent/x/2", Owner: "b"})
+	s.Claim(Lease{Branch: "wt-3", Owner: "c", PID: 999999}) // dead
+
+	leases, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leases) != 2 {
+		t.Fatalf("expected 2 live leases, got %+v", leases)
+	}
+}
@@ -2,8 +2,12 @@ package lock
 
 import (
 	"crypto/md5"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -11,68 +15,298 @@ import (
 )
 
 const (
-	DefaultTimeout = 30 * time.Second
-	pollInterval   = 500 * time.Millisecond
+	// DefaultTimeout matches the default hook timeout, since a hook is the
+	// longest step that can run while the lock is held.
+	DefaultTimeout = 10 * time.Minute
+	pollInterval   = 50 * time.Millisecond
 )
 
-// Lock represents a filesystem-based lock using mkdir atomicity.
+// Lock serializes wt-cycle invocations on one repository. It is an
+// flock(2) on a file under $XDG_RUNTIME_DIR, so the kernel drops it when
+// the holder exits, however that happens; a live holder is never broken.
+// Waiters take numbered tickets and get the lock in the order they arrived.
 type Lock struct {
-	dir string
+	path string   // <dir>/<hash>.lock, flocked by the holder
+	file *os.File // open while held
+}
+
+// Holder is the metadata the holder writes into the lock file.
+type Holder struct {
+	PID     int       `json:"pid"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+// Describe summarizes the holder for messages.
+func (h *Holder) Describe() string {
+	return fmt.Sprintf("PID %d (%s) since %s", h.PID, h.Command, h.Started.Local().Format("15:04:05"))
+}
+
+// Dir returns where lock files live: $XDG_RUNTIME_DIR/wt-cycle, or a
+// per-user directory in os.TempDir when that is unset.
+func Dir() string {
+	if d := os.Getenv("XDG_RUNTIME_DIR"); d != "" {
+		return filepath.Join(d, "wt-cycle")
+	}
+	return filepath.Join(os.TempDir(), "wt-cycle-"+strconv.Itoa(os.Getuid()))
 }
 
 // New creates a lock for the given repo root.
 func New(repoRoot string) *Lock {
 	hash := fmt.Sprintf("%x", md5.Sum([]byte(repoRoot)))
-	return &Lock{dir: fmt.Sprintf("/tmp/wt-cycle-lock-%s", hash)}
+	return &Lock{path: filepath.Join(Dir(), hash+".lock")}
+}
+
+// Path returns the lock file.
+func (l *Lock) Path() string { return l.path }
+
+// legacyGrace is how long an older version may take to write the pid file
+// after creating its lock directory.
+const legacyGrace = 30 * time.Second
+
+// Legacy describes the lock directory of a wt-cycle version before the
+// flock lock, which kept a "pid" file inside it.
+type Legacy struct {
+	Path  string
+	PID   int // 0 if unknown
+	Age   time.Duration
+	Stale bool // its holder has exited, or it never recorded one within legacyGrace
+}
+
+// Legacy reports on the older versions' lock directory for this repo, or
+// returns nil if there is none.
+func (l *Lock) Legacy() *Legacy {
+	dir := "/tmp/wt-cycle-lock-" + strings.TrimSuffix(filepath.Base(l.path), ".lock")
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil
+	}
+	lg := &Legacy{Path: dir, Age: time.Since(info.ModTime())}
+	if data, err := os.ReadFile(filepath.Join(dir, "pid")); err == nil {
+		lg.PID, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	if lg.PID > 0 {
+		lg.Stale = syscall.Kill(lg.PID, 0) == syscall.ESRCH
+	} else {
+		lg.Stale = lg.Age > legacyGrace
+	}
+	return lg
 }
 
-// Acquire attempts to acquire the lock, blocking up to timeout.
+// queueDir holds one ticket file per waiter, named by its place in line.
+// Each waiter keeps an flock on its ticket, so an unlocked ticket belongs
+// to a process that has gone away.
+func (l *Lock) queueDir() string { return strings.TrimSuffix(l.path, ".lock") + ".queue" }
+
+func (l *Lock) seqPath() string { return strings.TrimSuffix(l.path, ".lock") + ".seq" }
+
+// Acquire waits in line for the lock, for at most timeout. On timeout it
+// returns an error naming the holder rather than taking the lock.
 func (l *Lock) Acquire(timeout time.Duration) error {
+	if err := os.MkdirAll(l.queueDir(), 0700); err != nil {
+		return err
+	}
+	ticket, name, err := l.takeTicket()
+	if err != nil {
+		return fmt.Errorf("joining the lock queue: %w", err)
+	}
+	defer func() {
+		os.Remove(filepath.Join(l.queueDir(), name))
+		ticket.Close()
+	}()
+
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
 	deadline := time.Now().Add(timeout)
 	for {
-		if err := os.Mkdir(l.dir, 0755); err == nil {
-			// Write our PID
-			_ = os.WriteFile(l.dir+"/pid", []byte(strconv.Itoa(os.Getpid())), 0644)
-			return nil
+		if l.firstInLine(name) {
+			err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, syscall.EWOULDBLOCK) {
+				f.Close()
+				return err
+			}
 		}
-
-		// Check for stale lock
-		if l.isStale() {
-			os.RemoveAll(l.dir)
-			continue
-		}
-
 		if time.Now().After(deadline) {
-			// Timeout — break the lock
-			os.RemoveAll(l.dir)
-			continue
+			f.Close()
+			if h := readHolder(l.path); h != nil {
+				return fmt.Errorf("timed out after %s waiting for the lock held by %s", timeout, h.Describe())
+			}
+			return fmt.Errorf("timed out after %s waiting in line for the lock", timeout)
 		}
-
 		time.Sleep(pollInterval)
 	}
+
+	h := Holder{PID: os.Getpid(), Command: command(), Started: time.Now()}
+	data, _ := json.Marshal(h)
+	f.Truncate(0)
+	f.WriteAt(append(data, '\n'), 0)
+	l.file = f
+	return nil
 }
 
-// Release releases the lock.
+// Release releases the lock. The lock file stays: removing it could let
+// a waiter that already opened it lock a file nobody else can see.
 func (l *Lock) Release() {
-	os.RemoveAll(l.dir)
+	if l.file == nil {
+		return
+	}
+	l.file.Truncate(0)
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+	l.file = nil
+}
+
+// takeTicket adds a locked ticket to the end of the queue. The ticket is
+// locked before it is renamed into place, so it never looks abandoned.
+func (l *Lock) takeTicket() (*os.File, string, error) {
+	n, err := l.nextSeq()
+	if err != nil {
+		return nil, "", err
+	}
+	name := fmt.Sprintf("%020d", n)
+	tmp := filepath.Join(l.queueDir(), fmt.Sprintf(".new-%d-%s", os.Getpid(), name))
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return nil, "", err
+	}
+	if err := os.Rename(tmp, filepath.Join(l.queueDir(), name)); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return nil, "", err
+	}
+	return f, name, nil
 }
 
-// isStale checks if the lock holder PID is still alive.
-func (l *Lock) isStale() bool {
-	data, err := os.ReadFile(l.dir + "/pid")
+// nextSeq hands out ticket numbers from a counter file.
+func (l *Lock) nextSeq() (uint64, error) {
+	f, err := os.OpenFile(l.seqPath(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return 0, err
+	}
+	data, err := io.ReadAll(f)
 	if err != nil {
+		return 0, err
+	}
+	n, _ := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err := f.Truncate(0); err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteAt([]byte(strconv.FormatUint(n+1, 10)), 0); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// firstInLine reports whether no live ticket precedes ours, removing
+// abandoned ones along the way.
+func (l *Lock) firstInLine(mine string) bool {
+	for _, t := range l.tickets() {
+		if t == mine {
+			return true
+		}
+		if abandoned(filepath.Join(l.queueDir(), t)) {
+			os.Remove(filepath.Join(l.queueDir(), t))
+			continue
+		}
 		return false
 	}
-	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return true
+}
+
+// tickets lists the queue in order.
+func (l *Lock) tickets() []string {
+	entries, _ := os.ReadDir(l.queueDir()) // sorted by name
+	var names []string
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+// abandoned reports whether nobody holds the flock on path.
+func abandoned(path string) bool {
+	f, err := os.Open(path)
 	if err != nil {
-		return true // corrupt PID file
+		return false
 	}
-	// Check if process exists
-	proc, err := os.FindProcess(pid)
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		return false
+	}
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return true
+}
+
+// command describes this process for the holder metadata.
+func command() string {
+	if len(os.Args) == 0 {
+		return "unknown"
+	}
+	return strings.Join(append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...), " ")
+}
+
+func readHolder(path string) *Holder {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return true
+		return nil
+	}
+	var h Holder
+	if err := json.Unmarshal(data, &h); err != nil || h.PID == 0 {
+		return nil
+	}
+	return &h
+}
+
+// Status describes the lock as seen from outside.
+type Status struct {
+	Path    string  `json:"path"`
+	Held    bool    `json:"held"`
+	Holder  *Holder `json:"holder,omitempty"` // nil if free or not yet written
+	Waiting int     `json:"waiting"`
+}
+
+// Status reports whether the lock is held, by whom, and how many
+// processes are waiting for it.
+func (l *Lock) Status() (Status, error) {
+	s := Status{Path: l.path}
+	for _, t := range l.tickets() {
+		if !abandoned(filepath.Join(l.queueDir(), t)) {
+			s.Waiting++
+		}
+	}
+
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	defer f.Close()
+	// A shared lock can only be taken while nobody holds the lock.
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err == nil {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		return s, nil
+	} else if !errors.Is(err, syscall.EWOULDBLOCK) {
+		return s, err
 	}
-	// On Unix, signal 0 checks existence without sending a signal
-	err = proc.Signal(syscall.Signal(0))
-	return err != nil
+	s.Held = true
+	s.Holder = readHolder(l.path)
+	return s, nil
 }
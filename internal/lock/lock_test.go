@@ -4,86 +4,225 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 )
 
+func testLock(t *testing.T) *Lock {
+	t.Helper()
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	return New("/test/repo/" + t.Name())
+}
+
+// waitFor polls until cond holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
 func TestAcquireRelease(t *testing.T) {
-	l := New("/test/repo/" + t.Name())
+	l := testLock(t)
 	defer l.Release()
 
 	if err := l.Acquire(5 * time.Second); err != nil {
 		t.Fatal(err)
 	}
-
-	// Verify lock dir exists
-	if _, err := os.Stat(l.dir); os.IsNotExist(err) {
-		t.Fatal("lock dir should exist after acquire")
+	if !strings.HasPrefix(l.Path(), os.Getenv("XDG_RUNTIME_DIR")) {
+		t.Errorf("lock file %s not under $XDG_RUNTIME_DIR", l.Path())
 	}
 
 	l.Release()
+	if s, err := l.Status(); err != nil || s.Held {
+		t.Fatalf("status after release = %+v, %v", s, err)
+	}
+	// Releasing twice is harmless, and the lock can be taken again.
+	l.Release()
+	if err := l.Acquire(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	l := testLock(t)
+	defer l.Release()
 
-	// Verify lock dir removed
-	if _, err := os.Stat(l.dir); !os.IsNotExist(err) {
-		t.Fatal("lock dir should not exist after release")
+	if s, err := l.Status(); err != nil || s.Held || s.Holder != nil {
+		t.Fatalf("unexpected holder: %+v, %v", s, err)
+	}
+
+	before := time.Now()
+	if err := l.Acquire(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	s, err := l.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Held || s.Holder == nil || s.Holder.PID != os.Getpid() || s.Waiting != 0 {
+		t.Fatalf("status while held = %+v", s)
+	}
+	if s.Holder.Command == "" || s.Holder.Started.Before(before.Add(-time.Second)) {
+		t.Errorf("holder = %+v", s.Holder)
 	}
 }
 
 func TestContention(t *testing.T) {
-	l1 := New("/test/contention/" + t.Name())
-	l2 := New("/test/contention/" + t.Name())
+	l1 := testLock(t)
+	l2 := New("/test/repo/" + t.Name())
 
 	if err := l1.Acquire(5 * time.Second); err != nil {
 		t.Fatal(err)
 	}
 
 	// l2 should block until l1 releases
-	var wg sync.WaitGroup
-	wg.Add(1)
-	acquired := make(chan struct{})
-	go func() {
-		defer wg.Done()
-		if err := l2.Acquire(5 * time.Second); err != nil {
-			t.Errorf("l2 acquire failed: %v", err)
-			return
-		}
-		close(acquired)
-	}()
-
-	// Give goroutine time to block
-	time.Sleep(100 * time.Millisecond)
+	acquired := make(chan error)
+	go func() { acquired <- l2.Acquire(5 * time.Second) }()
 
+	waitFor(t, "l2 to queue", func() bool {
+		s, _ := l1.Status()
+		return s.Waiting == 1
+	})
 	select {
 	case <-acquired:
 		t.Fatal("l2 should not have acquired yet")
-	default:
-		// Good — still blocking
+	case <-time.After(100 * time.Millisecond):
 	}
 
 	l1.Release()
-	wg.Wait()
+	if err := <-acquired; err != nil {
+		t.Fatalf("l2 acquire failed: %v", err)
+	}
+	l2.Release()
+}
 
-	select {
-	case <-acquired:
-		// Good — acquired after release
-	default:
-		t.Fatal("l2 should have acquired after l1 released")
+// TestTimeoutNeverBreaks checks that a waiter gives up rather than taking
+// the lock from its holder, however long it has been held.
+func TestTimeoutNeverBreaks(t *testing.T) {
+	l1 := testLock(t)
+	l2 := New("/test/repo/" + t.Name())
+	if err := l1.Acquire(time.Second); err != nil {
+		t.Fatal(err)
 	}
+	defer l1.Release()
 
-	l2.Release()
+	err := l2.Acquire(200 * time.Millisecond)
+	if err == nil {
+		l2.Release()
+		t.Fatal("expected a timeout")
+	}
+	if !strings.Contains(err.Error(), "PID") {
+		t.Errorf("error %q does not name the holder", err)
+	}
+	if s, _ := l1.Status(); !s.Held || s.Waiting != 0 {
+		t.Errorf("status after timeout = %+v", s)
+	}
 }
 
-func TestStaleLock(t *testing.T) {
-	l := New("/test/stale/" + t.Name())
+func TestFIFO(t *testing.T) {
+	l1 := testLock(t)
+	if err := l1.Acquire(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := 2; i <= 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := New("/test/repo/" + t.Name())
+			if err := l.Acquire(5 * time.Second); err != nil {
+				t.Errorf("l%d: %v", i, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			l.Release()
+		}()
+		// Queue each waiter before starting the next.
+		waitFor(t, "waiters to queue", func() bool {
+			s, _ := l1.Status()
+			return s.Waiting == i-1
+		})
+	}
+
+	l1.Release()
+	wg.Wait()
+	if len(order) != 3 || order[0] != 2 || order[1] != 3 || order[2] != 4 {
+		t.Errorf("acquired in order %v, want [2 3 4]", order)
+	}
+}
+
+// TestDeadProcesses checks that neither an abandoned ticket nor holder
+// metadata left by a process that has exited gets in the way.
+func TestDeadProcesses(t *testing.T) {
+	l := testLock(t)
 	defer l.Release()
 
-	// Manually create a stale lock with a dead PID
-	os.MkdirAll(l.dir, 0755)
-	os.WriteFile(filepath.Join(l.dir, "pid"), []byte(strconv.Itoa(999999)), 0644)
+	os.MkdirAll(l.queueDir(), 0700)
+	os.WriteFile(filepath.Join(l.queueDir(), "00000000000000000000"), nil, 0600)
+	os.WriteFile(l.Path(), []byte(`{"pid":999999999,"command":"wt-cycle next","started":"2026-01-01T00:00:00Z"}`), 0600)
 
-	// Should break the stale lock and acquire
-	if err := l.Acquire(2 * time.Second); err != nil {
+	if s, err := l.Status(); err != nil || s.Held || s.Waiting != 0 {
+		t.Fatalf("status with dead processes = %+v, %v", s, err)
+	}
+	if err := l.Acquire(time.Second); err != nil {
 		t.Fatal(err)
 	}
+	if entries, _ := os.ReadDir(l.queueDir()); len(entries) != 0 {
+		t.Errorf("queue not cleaned up: %v", entries)
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	l := testLock(t)
+	dir := "/tmp/wt-cycle-lock-" + strings.TrimSuffix(filepath.Base(l.Path()), ".lock")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		pid   string // "" for no pid file
+		aged  bool
+		stale bool
+	}{
+		{"live holder", strconv.Itoa(os.Getpid()), false, false},
+		{"old live holder", strconv.Itoa(os.Getpid()), true, false},
+		{"dead holder", "999999999", false, true}, // above pid_max
+		{"pid not written yet", "", false, false},
+		{"pid never written", "", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pidFile := filepath.Join(dir, "pid")
+			os.Remove(pidFile)
+			if tt.pid != "" {
+				os.WriteFile(pidFile, []byte(tt.pid), 0644)
+			}
+			mtime := time.Now()
+			if tt.aged {
+				mtime = old
+			}
+			os.Chtimes(dir, mtime, mtime)
+
+			lg := l.Legacy()
+			if lg == nil || lg.Path != dir {
+				t.Fatalf("Legacy() = %+v", lg)
+			}
+			if lg.Stale != tt.stale {
+				t.Errorf("Stale = %v, want %v", lg.Stale, tt.stale)
+			}
+		})
+	}
 }
@@ -0,0 +1,122 @@
+package procs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// procRoot is the procfs mount point; overridden in tests.
+var procRoot = "/proc"
+
+// Process is a running process and the paths it is using.
+type Process struct {
+	PID     int
+	Command string
+	Cwd     string
+	Files   []string // open file paths; only populated when requested
+}
+
+// List returns all processes readable from /proc, including their cwd and,
+// if withFiles is set, the paths of their open file descriptors. Processes
+// owned by other users are silently skipped. On systems without /proc
+// (e.g. macOS) it returns nil.
+func List(withFiles bool) ([]Process, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result []Process
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join(procRoot, e.Name())
+		cwd, err := os.Readlink(filepath.Join(dir, "cwd"))
+		if err != nil {
+			continue // exited, or not ours to inspect
+		}
+		comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+		p := Process{
+			PID:     pid,
+			Command: strings.TrimSpace(string(comm)),
+			Cwd:     cwd,
+		}
+		if withFiles {
+			p.Files = openFiles(dir)
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func openFiles(procDir string) []string {
+	fds, err := os.ReadDir(filepath.Join(procDir, "fd"))
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(procDir, "fd", fd.Name()))
+		if err == nil && filepath.IsAbs(target) {
+			files = append(files, target)
+		}
+	}
+	return files
+}
+
+// Under returns the processes whose cwd or open files are inside dir.
+func Under(procs []Process, dir string) []Process {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	var result []Process
+	for _, p := range procs {
+		if within(p.Cwd, dir) {
+			result = append(result, p)
+			continue
+		}
+		for _, f := range p.Files {
+			if within(f, dir) {
+				result = append(result, p)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Describe formats processes as "PID command" pairs for display.
+func Describe(procs []Process) string {
+	parts := make([]string, len(procs))
+	for i, p := range procs {
+		parts[i] = strconv.Itoa(p.PID) + " " + p.Command
+	}
+	return strings.Join(parts, ", ")
+}
+
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// Alive reports whether a process with the given PID exists.
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On Unix, signal 0 checks existence without sending a signal
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
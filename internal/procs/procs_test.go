@@ -0,0 +1,85 @@
+package procs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestList_FindsSelf(t *testing.T) {
+	if _, err := os.Stat("/proc/self/cwd"); err != nil {
+		t.Skip("no procfs")
+	}
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	all, err := List(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range Under(all, dir) {
+		if p.PID == os.Getpid() {
+			return
+		}
+	}
+	t.Errorf("expected pid %d under %s", os.Getpid(), dir)
+}
+
+func TestList_FakeProcfs(t *testing.T) {
+	root := t.TempDir()
+	procRoot = root
+	t.Cleanup(func() { procRoot = "/proc" })
+
+	wt := filepath.Join(t.TempDir(), "repo.wt-1")
+	os.MkdirAll(wt, 0755)
+	wt, _ = filepath.EvalSymlinks(wt)
+
+	mkproc := func(pid, comm, cwd string, files ...string) {
+		dir := filepath.Join(root, pid)
+		os.MkdirAll(filepath.Join(dir, "fd"), 0755)
+		os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0644)
+		os.Symlink(cwd, filepath.Join(dir, "cwd"))
+		for i, f := range files {
+			os.Symlink(f, filepath.Join(dir, "fd", string(rune('0'+i))))
+		}
+	}
+	mkproc("100", "node", filepath.Join(wt, "src"))
+	mkproc("200", "vim", "/home", filepath.Join(wt, "main.go"))
+	mkproc("300", "bash", wt+"-other")
+	os.MkdirAll(filepath.Join(root, "self"), 0755) // non-numeric, ignored
+
+	all, err := List(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Under(all, wt); len(got) != 1 || got[0].PID != 100 || got[0].Command != "node" {
+		t.Errorf("cwd only: Under = %+v, want [100 node]", got)
+	}
+
+	all, err = List(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Under(all, wt)
+	if len(got) != 2 {
+		t.Fatalf("with files: Under = %+v, want 2 processes", got)
+	}
+	if d := Describe(got); d != "100 node, 200 vim" {
+		t.Errorf("Describe = %q", d)
+	}
+}
+
+func TestAlive(t *testing.T) {
+	if !Alive(os.Getpid()) {
+		t.Error("expected own pid to be alive")
+	}
+	if Alive(999999) {
+		t.Error("expected pid 999999 to be dead")
+	}
+	if Alive(0) {
+		t.Error("expected pid 0 to be reported dead")
+	}
+}
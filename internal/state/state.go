@@ -0,0 +1,42 @@
+package state
+
+import (
+	"crypto/md5"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the per-repo directory for persistent state such as leases
+// and the operation journal: $XDG_STATE_HOME/wt-cycle/<hash>/, falling
+// back to ~/.local/state. Unlike the cache, its contents must survive.
+func Dir(repoRoot string) string {
+	hash := fmt.Sprintf("%x", md5.Sum([]byte(repoRoot)))
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, _ := os.UserHomeDir()
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "wt-cycle", hash)
+}
+
+// WriteFileAtomic writes data to path via a temp file and rename, so
+// readers never observe a partial write.
+func WriteFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
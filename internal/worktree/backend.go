@@ -0,0 +1,80 @@
+package worktree
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Backend abstracts the tool that creates, switches and removes worktrees.
+type Backend interface {
+	// Switch prepares the existing worktree for branch for use.
+	Switch(branch string) error
+	// Create adds a worktree for the new branch, starting at base. Backends
+	// that choose their own directory layout may ignore path.
+	Create(branch, base, path string) error
+	// Remove deletes the worktree for branch located at path.
+	Remove(branch, path string) error
+}
+
+// Backend names accepted in config.
+const (
+	NameWorktrunk = "worktrunk"
+	NameGit       = "git"
+)
+
+// GitRunner is the subset of git.Client needed by the native backend.
+type GitRunner interface {
+	Run(args ...string) (string, error)
+}
+
+// Worktrunk implements Backend by shelling out to worktrunk's `wt` binary.
+type Worktrunk struct {
+	Run func(args ...string) error
+}
+
+func (w *Worktrunk) Switch(branch string) error {
+	if err := w.Run("switch", branch); err != nil {
+		return fmt.Errorf("wt switch %s: %w", branch, err)
+	}
+	return nil
+}
+
+func (w *Worktrunk) Create(branch, base, _ string) error {
+	if err := w.Run("switch", "-c", branch, "--base", base); err != nil {
+		return fmt.Errorf("wt switch -c %s: %w", branch, err)
+	}
+	return nil
+}
+
+func (w *Worktrunk) Remove(branch, _ string) error {
+	if err := w.Run("remove", "-y", branch); err != nil {
+		return fmt.Errorf("wt remove %s: %w", branch, err)
+	}
+	return nil
+}
+
+// Native implements Backend with plain `git worktree` commands.
+type Native struct {
+	Git GitRunner
+}
+
+// Switch is a no-op: a git worktree needs no preparation beyond chdir.
+func (n *Native) Switch(string) error {
+	return nil
+}
+
+func (n *Native) Create(branch, base, path string) error {
+	_, err := n.Git.Run("worktree", "add", "-q", "-b", branch, path, base)
+	return err
+}
+
+func (n *Native) Remove(_, path string) error {
+	_, err := n.Git.Run("worktree", "remove", path)
+	return err
+}
+
+// WorktrunkAvailable reports whether the `wt` binary is on PATH.
+func WorktrunkAvailable() bool {
+	_, err := exec.LookPath("wt")
+	return err == nil
+}
@@ -0,0 +1,79 @@
+package worktree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakeGit struct {
+	calls [][]string
+	err   error
+}
+
+func (f *fakeGit) Run(args ...string) (string, error) {
+	f.calls = append(f.calls, args)
+	return "", f.err
+}
+
+func TestWorktrunk_Args(t *testing.T) {
+	var calls [][]string
+	w := &Worktrunk{Run: func(args ...string) error {
+		calls = append(calls, args)
+		return nil
+	}}
+
+	w.Switch("wt-1")
+	w.Create("wt-2", "origin/main", "/ignored")
+	w.Remove("wt-3", "/ignored")
+
+	want := []string{
+		"switch wt-1",
+		"switch -c wt-2 --base origin/main",
+		"remove -y wt-3",
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i, c := range calls {
+		if got := strings.Join(c, " "); got != want[i] {
+			t.Errorf("call[%d] = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestWorktrunk_ErrorMentionsCommand(t *testing.T) {
+	w := &Worktrunk{Run: func(args ...string) error { return fmt.Errorf("boom") }}
+
+	if err := w.Create("wt-2", "origin/main", ""); err == nil || !strings.Contains(err.Error(), "wt switch -c wt-2") {
+		t.Errorf("Create error = %v, want it to mention 'wt switch -c wt-2'", err)
+	}
+}
+
+func TestNative_Args(t *testing.T) {
+	g := &fakeGit{}
+	n := &Native{Git: g}
+
+	if err := n.Switch("wt-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Create("wt-2", "origin/main", "/src/repo.wt-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Remove("wt-3", "/src/repo.wt-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"worktree add -q -b wt-2 /src/repo.wt-2 origin/main",
+		"worktree remove /src/repo.wt-3",
+	}
+	if len(g.calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", g.calls, want)
+	}
+	for i, c := range g.calls {
+		if got := strings.Join(c, " "); got != want[i] {
+			t.Errorf("call[%d] = %q, want %q", i, got, want[i])
+		}
+	}
+}